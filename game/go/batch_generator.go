@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+type BatchOptions struct {
+	Config GeneratorConfig
+	Count  int    // number of distinct levels to write
+	OutDir string // created if it doesn't exist
+	// Seed is the first seed to try; subsequent levels use Seed+1, Seed+2, etc.
+	Seed int64
+	// MaxSeeds limits how many seeds are tried, since a small config may not have Count distinct levels.
+	// Defaults to ten times Count.
+	MaxSeeds int
+	Workers  int                            // number of goroutines, or 0 for runtime.NumCPU()
+	Progress func(entry BatchManifestEntry) // called for each level written, if not nil
+}
+
+// BatchManifest describes a batch of generated levels. It's written as manifest.json in the output directory.
+type BatchManifest struct {
+	Config     GeneratorConfig      `json:"config"`
+	Levels     []BatchManifestEntry `json:"levels"`
+	SeedsTried int                  `json:"seedsTried"`
+	Duplicates int                  `json:"duplicates"` // levels skipped as equivalent to an earlier one
+	Failures   int                  `json:"failures"`   // seeds that didn't give a valid level
+}
+
+type BatchManifestEntry struct {
+	File        string             `json:"file"`
+	Playthrough string             `json:"playthrough,omitempty"` // the solution, if the config has Solve set
+	Seed        int64              `json:"seed"`
+	Par         int                `json:"par,omitempty"`
+	Score       float64            `json:"score"`
+	Metrics     map[string]float64 `json:"metrics,omitempty"`
+}
+
+const batchManifestFile = "manifest.json"
+
+type batchResult struct {
+	index     int
+	generated GeneratedLevel
+	err       error
+}
+
+// GenerateBatch generates levels from consecutive seeds in parallel, writing distinct ones to opts.OutDir,
+// until it has opts.Count of them, and then writes a manifest.
+// Levels are considered duplicates if they're the same up to translation, rotation, reflection,
+// swapping black and white, and renaming snakes; see levelDedupKey.
+// Results are taken in seed order, so the output doesn't depend on the number of workers.
+// If ctx is cancelled, the manifest is still written for the levels written so far.
+func GenerateBatch(ctx context.Context, opts BatchOptions) (BatchManifest, error) {
+	manifest := BatchManifest{Config: opts.Config, Levels: []BatchManifestEntry{}}
+	if err := opts.Config.Validate(); err != nil {
+		return manifest, err
+	}
+	if opts.Count <= 0 {
+		return manifest, fmt.Errorf("count must be positive, but got %d", opts.Count)
+	}
+	maxSeeds := opts.MaxSeeds
+	if maxSeeds <= 0 {
+		maxSeeds = opts.Count * 10
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if err := os.MkdirAll(opts.OutDir, 0755); err != nil {
+		return manifest, err
+	}
+
+	// Stop handing out seeds once there are enough levels, or on cancellation.
+	workCtx, stopWork := context.WithCancel(ctx)
+	defer stopWork()
+	indices := make(chan int)
+	go func() {
+		defer close(indices)
+		for i := 0; i < maxSeeds; i++ {
+			select {
+			case indices <- i:
+			case <-workCtx.Done():
+				return
+			}
+		}
+	}()
+	results := make(chan batchResult)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				generated, err := GenerateLevelFromSeed(opts.Seed+int64(i), opts.Config)
+				results <- batchResult{index: i, generated: generated, err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Results arrive out of order, so hold them until the ones before them are done.
+	pending := map[int]batchResult{}
+	seen := map[string]bool{}
+	var writeErr error
+	for result := range results {
+		pending[result.index] = result
+		for {
+			next, ok := pending[manifest.SeedsTried]
+			if !ok || len(manifest.Levels) >= opts.Count || writeErr != nil {
+				break
+			}
+			delete(pending, manifest.SeedsTried)
+			manifest.SeedsTried++
+			if next.err != nil {
+				manifest.Failures++
+				continue
+			}
+			key := levelDedupKey(next.generated.Level)
+			if seen[key] {
+				manifest.Duplicates++
+				continue
+			}
+			seen[key] = true
+			entry, err := writeBatchLevel(opts.OutDir, opts.Seed+int64(next.index), next.generated)
+			if err != nil {
+				writeErr = err
+				stopWork()
+				break
+			}
+			manifest.Levels = append(manifest.Levels, entry)
+			if opts.Progress != nil {
+				opts.Progress(entry)
+			}
+			if len(manifest.Levels) >= opts.Count {
+				stopWork()
+			}
+		}
+	}
+
+	if writeErr != nil {
+		return manifest, writeErr
+	}
+	if err := writeBatchManifest(opts.OutDir, manifest); err != nil {
+		return manifest, err
+	}
+	if err := ctx.Err(); err != nil {
+		return manifest, fmt.Errorf("generation cancelled after %d levels: %w", len(manifest.Levels), err)
+	}
+	if len(manifest.Levels) < opts.Count {
+		return manifest, fmt.Errorf("only found %d distinct levels in %d seeds", len(manifest.Levels), manifest.SeedsTried)
+	}
+	return manifest, nil
+}
+
+func writeBatchLevel(dir string, seed int64, generated GeneratedLevel) (BatchManifestEntry, error) {
+	entry := BatchManifestEntry{
+		File:    "generated-" + strconv.FormatInt(seed, 10) + ".json",
+		Seed:    seed,
+		Par:     generated.Level.Info.Par,
+		Score:   generated.Score,
+		Metrics: generated.Metrics,
+	}
+	serialized, err := SerializeLevel(generated.Level)
+	if err != nil {
+		return entry, fmt.Errorf("failed to serialize level: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, entry.File), serialized, 0644); err != nil {
+		return entry, err
+	}
+	if generated.Solution != nil {
+		entry.Playthrough = "generated-" + strconv.FormatInt(seed, 10) + "-playthrough.json"
+		playthrough, err := SerializePlaythrough(generated.Level, generated.Solution)
+		if err != nil {
+			return entry, fmt.Errorf("failed to serialize playthrough: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, entry.Playthrough), playthrough, 0644); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+func writeBatchManifest(dir string, manifest BatchManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, batchManifestFile), append(data, '\n'), 0644)
+}
+
+// levelDedupKey returns a key that's the same for levels that are the same puzzle,
+// up to translation, rotation, reflection, swapping black and white, and renaming snakes.
+// Translation only counts margins of walls, which nothing can enter; any other margin gives snakes room to move.
+// Entity order (which is on top) is kept, although it only matters for overlapping entities,
+// so levels that differ only in the order of separate entities aren't considered duplicates.
+func levelDedupKey(level *Level) string {
+	canonical, _ := Canonicalize(renumberSnakes(cropWalledMargins(level)))
+	return string(CanonicalBytes(canonical))
+}
+
+// renumberSnakes returns a copy of the level with snakes renamed "1", "2", etc. in entity order.
+func renumberSnakes(level *Level) *Level {
+	level = copyLevel(level)
+	ids := map[string]string{}
+	for _, snake := range getSnakes(level) {
+		ids[snake.ID] = strconv.Itoa(len(ids) + 1)
+	}
+	for _, snake := range getSnakes(level) {
+		snake.ID = ids[snake.ID]
+		for i, id := range snake.FusedSnakeIDs {
+			if newID, ok := ids[id]; ok {
+				snake.FusedSnakeIDs[i] = newID
+			}
+		}
+	}
+	return level
+}
+
+// cropWalledMargins returns a copy of the level without rows and columns at its edges
+// which are solid for both layers and have no entities in them, so they're no different from the edge of the level.
+func cropWalledMargins(level *Level) *Level {
+	occupied := map[Point]bool{}
+	for _, entity := range level.Entities {
+		switch e := entity.(type) {
+		case *Snake:
+			for _, segment := range e.Segments {
+				occupied[segment] = true
+			}
+		case *Food:
+			occupied[e.Position] = true
+		case *Inverter:
+			occupied[e.Position] = true
+		case *Crate:
+			occupied[e.Position] = true
+		case *CellularAutomata:
+			occupied[e.Position] = true
+		}
+	}
+	walledLine := func(points []Point) bool {
+		for _, p := range points {
+			if occupied[p] || level.Grid[p.Y][p.X] != Both {
+				return false
+			}
+		}
+		return true
+	}
+	column := func(x, top, bottom int) []Point {
+		var points []Point
+		for y := top; y < bottom; y++ {
+			points = append(points, Point{X: x, Y: y})
+		}
+		return points
+	}
+	row := func(y, left, right int) []Point {
+		var points []Point
+		for x := left; x < right; x++ {
+			points = append(points, Point{X: x, Y: y})
+		}
+		return points
+	}
+
+	left, top, right, bottom := 0, 0, level.Info.Width, level.Info.Height
+	for changed := true; changed; {
+		changed = false
+		if right-left > 1 && walledLine(column(left, top, bottom)) {
+			left, changed = left+1, true
+		}
+		if right-left > 1 && walledLine(column(right-1, top, bottom)) {
+			right, changed = right-1, true
+		}
+		if bottom-top > 1 && walledLine(row(top, left, right)) {
+			top, changed = top+1, true
+		}
+		if bottom-top > 1 && walledLine(row(bottom-1, left, right)) {
+			bottom, changed = bottom-1, true
+		}
+	}
+
+	cropped := copyLevel(level)
+	cropped.Info.Width, cropped.Info.Height = right-left, bottom-top
+	cropped.Grid = cropped.Grid[top:bottom]
+	for y := range cropped.Grid {
+		cropped.Grid[y] = slices.Clone(cropped.Grid[y][left:right])
+	}
+	offset := func(p Point) Point { return Point{X: p.X - left, Y: p.Y - top} }
+	for _, entity := range cropped.Entities {
+		switch e := entity.(type) {
+		case *Food:
+			e.Position = offset(e.Position)
+		case *Inverter:
+			e.Position = offset(e.Position)
+		case *Crate:
+			e.Position = offset(e.Position)
+		case *CellularAutomata:
+			e.Position = offset(e.Position)
+		case *Snake:
+			for i, segment := range e.Segments {
+				e.Segments[i] = offset(segment)
+			}
+		}
+	}
+	return cropped
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLevelDedupKey(t *testing.T) {
+	level, err := LoadLevel("levels/easy/004-ferry.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	key := levelDedupKey(level)
+	for _, op := range SymmetryOps {
+		if levelDedupKey(Transform(level, op)) != key {
+			t.Errorf("Expected the level transformed by %v to be a duplicate", op)
+		}
+	}
+
+	renamed := copyLevel(level)
+	for _, snake := range getSnakes(renamed) {
+		snake.ID = "renamed-" + snake.ID
+	}
+	if levelDedupKey(renamed) != key {
+		t.Errorf("Expected the level with renamed snakes to be a duplicate")
+	}
+
+	// Add a margin on the left and top.
+	pad := func(layer CollisionLayer) *Level {
+		padded := copyLevel(level)
+		padded.Info.Width++
+		padded.Info.Height++
+		margin := make([]CollisionLayer, padded.Info.Width)
+		for x := range margin {
+			margin[x] = layer
+		}
+		padded.Grid = append([][]CollisionLayer{margin}, padded.Grid...)
+		for y := 1; y < len(padded.Grid); y++ {
+			padded.Grid[y] = append([]CollisionLayer{layer}, padded.Grid[y]...)
+		}
+		for _, entity := range padded.Entities {
+			switch e := entity.(type) {
+			case *Food:
+				e.Position = Point{X: e.Position.X + 1, Y: e.Position.Y + 1}
+			case *Snake:
+				for i, segment := range e.Segments {
+					e.Segments[i] = Point{X: segment.X + 1, Y: segment.Y + 1}
+				}
+			}
+		}
+		return padded
+	}
+	if levelDedupKey(pad(Both)) != key {
+		t.Errorf("Expected the level with a margin of walls to be a duplicate")
+	}
+	// Snakes can move into an empty margin, so it's a different puzzle.
+	for _, layer := range []CollisionLayer{Neither, White, Black} {
+		if levelDedupKey(pad(layer)) == key {
+			t.Errorf("Expected the level with a margin of layer %v not to be a duplicate", layer)
+		}
+	}
+
+	changed := copyLevel(level)
+	changed.Grid[0][0] = invertCollisionLayer(changed.Grid[0][0])
+	if levelDedupKey(changed) == key {
+		t.Errorf("Expected a level with a different grid not to be a duplicate")
+	}
+}
+
+func TestGenerateBatch(t *testing.T) {
+	config := DefaultGeneratorConfig()
+	config.Tries = 5
+	var manifests []BatchManifest
+	for _, workers := range []int{1, 3} {
+		dir := t.TempDir()
+		manifest, err := GenerateBatch(context.Background(), BatchOptions{Config: config, Count: 4, OutDir: dir, Seed: 7, Workers: workers})
+		if err != nil {
+			t.Fatalf("Failed to generate batch: %v", err)
+		}
+		if len(manifest.Levels) != 4 {
+			t.Fatalf("Expected 4 levels, but got %d", len(manifest.Levels))
+		}
+		var written BatchManifest
+		data, err := os.ReadFile(filepath.Join(dir, batchManifestFile))
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		if err := json.Unmarshal(data, &written); err != nil {
+			t.Fatalf("Failed to parse manifest: %v", err)
+		}
+		if !reflect.DeepEqual(written, manifest) {
+			t.Errorf("Expected the written manifest to match the returned one")
+		}
+		for _, entry := range manifest.Levels {
+			if _, err := os.Stat(filepath.Join(dir, entry.File)); err != nil {
+				t.Errorf("Expected level file to be written: %v", err)
+			}
+		}
+		manifests = append(manifests, manifest)
+	}
+	if !reflect.DeepEqual(manifests[0], manifests[1]) {
+		t.Errorf("Expected the same levels regardless of the number of workers, but got %+v and %+v", manifests[0].Levels, manifests[1].Levels)
+	}
+}
+
+func TestGenerateBatchSkipsDuplicates(t *testing.T) {
+	// There are only so many levels this small.
+	config := DefaultGeneratorConfig()
+	config.MinWidth, config.MaxWidth, config.MinHeight, config.MaxHeight = 2, 2, 2, 2
+	config.MinSnakes, config.MaxSnakes, config.MinSnakeLength, config.MaxSnakeLength = 1, 1, 2, 2
+	config.Tries = 1
+	manifest, err := GenerateBatch(context.Background(), BatchOptions{Config: config, Count: 100, OutDir: t.TempDir(), MaxSeeds: 50})
+	if err == nil {
+		t.Errorf("Expected an error for not finding enough distinct levels")
+	}
+	if manifest.Duplicates == 0 || manifest.SeedsTried != 50 || len(manifest.Levels)+manifest.Duplicates+manifest.Failures != 50 {
+		t.Errorf("Expected duplicates to be skipped, but got %d levels, %d duplicates and %d failures from %d seeds",
+			len(manifest.Levels), manifest.Duplicates, manifest.Failures, manifest.SeedsTried)
+	}
+}
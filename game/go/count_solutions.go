@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+type CountSolutionsOptions struct {
+	SolveOptions
+	MaxCount int // stop counting winning states and shortest solutions at this many, or 0 for no limit
+}
+
+type SolutionCount struct {
+	ShortestLength int         // number of moves in a shortest solution
+	Shortest       int         // distinct move sequences of the shortest length that win
+	WinningStates  int         // distinct winning end states, reachable in any number of moves
+	Example        []MoveInput // one of the shortest solutions
+	// Complete is true if every reachable state was searched and no count was capped,
+	// so the counts are exact rather than lower bounds.
+	Complete bool
+}
+
+// Unique reports whether the level was shown to have exactly one shortest solution,
+// and no other way to finish it.
+func (count SolutionCount) Unique() bool {
+	return count.Complete && count.Shortest == 1 && count.WinningStates == 1
+}
+
+// CountSolutions searches the whole state space breadth-first, like Solve, but doesn't stop at the first solution.
+// It counts the distinct winning states, and the distinct shortest move sequences that reach them.
+// Moves of different snakes that don't interact count as different solutions in either order.
+// If a limit is reached after a solution is found, the counts so far are returned, with Complete false.
+// If no solution is found, it returns the same errors as Solve.
+func CountSolutions(level *Level, opts CountSolutionsOptions) (SolutionCount, error) {
+	count := SolutionCount{ShortestLength: -1}
+	if levelIsWon(level) {
+		return SolutionCount{Shortest: 1, WinningStates: 1, Example: []MoveInput{}, Complete: true}, nil
+	}
+	if dead, reasons := IsDeadState(level); dead {
+		return count, deadStateError(reasons)
+	}
+
+	nodes := []solverNode{{parent: -1}}
+	// Number of distinct shortest move sequences reaching each node's state, saturating at math.MaxInt
+	paths := []int{1}
+	queue := []*Level{copyLevel(level)}
+	queueNodes := []int{0}
+	// Node index of each state seen
+	visited := map[string]int{string(CanonicalBytes(level)): 0}
+	capped := func(n int) bool { return opts.MaxCount > 0 && n >= opts.MaxCount }
+	limited, stateLimitReached := false, false
+
+	for len(queue) > 0 && !stateLimitReached {
+		current := queue[0]
+		currentIndex := queueNodes[0]
+		queue = queue[1:]
+		queueNodes = queueNodes[1:]
+		depth := nodes[currentIndex].depth
+		// Once past the shortest solutions, the only thing left to count is winning states.
+		if count.ShortestLength != -1 && depth >= count.ShortestLength && capped(count.WinningStates) {
+			limited = true
+			break
+		}
+		if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+			limited = true
+			continue
+		}
+
+		for _, successor := range successors(current) {
+			key := string(CanonicalBytes(successor.level))
+			if index, ok := visited[key]; ok {
+				// Another shortest path to a state in the next layer
+				if nodes[index].depth == depth+1 {
+					paths[index] = saturatingAdd(paths[index], paths[currentIndex])
+					if levelIsWon(successor.level) && depth+1 == count.ShortestLength {
+						count.Shortest = saturatingAdd(count.Shortest, paths[currentIndex])
+					}
+				}
+				continue
+			}
+			if opts.MaxStates > 0 && len(visited) >= opts.MaxStates {
+				limited, stateLimitReached = true, true
+				break
+			}
+			visited[key] = len(nodes)
+			nodes = append(nodes, solverNode{parent: currentIndex, moveInput: successor.moveInput, depth: depth + 1})
+			paths = append(paths, paths[currentIndex])
+
+			if levelIsWon(successor.level) {
+				// The game is over once it's won, so winning states aren't searched further.
+				count.WinningStates++
+				if count.ShortestLength == -1 {
+					count.ShortestLength = depth + 1
+					count.Example = solutionTo(nodes, len(nodes)-1)
+				}
+				if depth+1 == count.ShortestLength {
+					count.Shortest = saturatingAdd(count.Shortest, paths[currentIndex])
+				}
+				continue
+			}
+			if isDeadState(successor.level) {
+				continue
+			}
+			queue = append(queue, successor.level)
+			queueNodes = append(queueNodes, len(nodes)-1)
+		}
+	}
+
+	if count.ShortestLength == -1 {
+		if limited {
+			return count, fmt.Errorf("%w: no solution found (visited %d states)", ErrSearchLimitReached, len(visited))
+		}
+		return count, fmt.Errorf("%w: exhausted all %d reachable states", ErrUnsolvable, len(visited))
+	}
+	if opts.MaxCount > 0 {
+		count.Shortest = min(count.Shortest, opts.MaxCount)
+		count.WinningStates = min(count.WinningStates, opts.MaxCount)
+	}
+	count.Complete = !limited && !capped(count.Shortest) && !capped(count.WinningStates)
+	return count, nil
+}
+
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
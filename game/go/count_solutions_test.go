@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCountSolutionsUnique(t *testing.T) {
+	level := &Level{
+		Info:     LevelInfo{Width: 3, Height: 1},
+		Grid:     [][]CollisionLayer{{Black, Black, Black}},
+		Entities: []Entity{&Snake{ID: "a", Layer: White, Segments: []Point{{X: 0, Y: 0}}}, &Food{Position: Point{X: 2, Y: 0}, Layer: White}},
+	}
+	count, err := CountSolutions(level, CountSolutionsOptions{})
+	if err != nil {
+		t.Fatalf("Failed to count solutions: %v", err)
+	}
+	if count.ShortestLength != 2 || len(count.Example) != 2 {
+		t.Errorf("Expected a 2 move solution, but got %d moves: %s", count.ShortestLength, String(count.Example))
+	}
+	if !count.Unique() {
+		t.Errorf("Expected a unique solution, but got %d shortest solutions and %d winning states", count.Shortest, count.WinningStates)
+	}
+}
+
+func TestCountSolutionsMultiple(t *testing.T) {
+	level, err := LoadLevel("levels/easy/001-movement.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	count, err := CountSolutions(level, CountSolutionsOptions{})
+	if err != nil {
+		t.Fatalf("Failed to count solutions: %v", err)
+	}
+	if count.ShortestLength != 22 {
+		t.Errorf("Expected shortest solution to be 22 moves, but got %d", count.ShortestLength)
+	}
+	if count.Shortest != 3 || count.WinningStates != 28 || !count.Complete {
+		t.Errorf("Expected exactly 3 shortest solutions and 28 winning states, but got %d and %d (complete: %v)", count.Shortest, count.WinningStates, count.Complete)
+	}
+	if count.Unique() {
+		t.Errorf("Expected solution not to be unique")
+	}
+
+	count, err = CountSolutions(level, CountSolutionsOptions{MaxCount: 2})
+	if err != nil {
+		t.Fatalf("Failed to count solutions: %v", err)
+	}
+	if count.Shortest != 2 || count.WinningStates != 2 || count.Complete {
+		t.Errorf("Expected counts to be capped at 2, but got %d and %d (complete: %v)", count.Shortest, count.WinningStates, count.Complete)
+	}
+}
+
+func TestCountSolutionsUnsolvable(t *testing.T) {
+	level := &Level{
+		Info:     LevelInfo{Width: 3, Height: 1},
+		Grid:     [][]CollisionLayer{{Black, Black, Black}},
+		Entities: []Entity{&Snake{ID: "a", Layer: White, Segments: []Point{{X: 0, Y: 0}}}, &Food{Position: Point{X: 2, Y: 0}, Layer: Black}},
+	}
+	_, err := CountSolutions(level, CountSolutionsOptions{})
+	if !errors.Is(err, ErrUnsolvable) {
+		t.Errorf("Expected ErrUnsolvable, but got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import "fmt"
+
+// IsDeadState reports whether a level can no longer be won, with the reasons why.
+// It's conservative: a state it doesn't consider dead may still be unwinnable.
+// Reasons are tagged with the position of the food or snake involved.
+func IsDeadState(level *Level) (bool, []Diagnostic) {
+	reasons := deadStateReasons(level, true)
+	return len(reasons) > 0, reasons
+}
+
+// isDeadState is a faster version of IsDeadState for pruning searches.
+// It stops at the first reason, and doesn't check whether any moves are possible,
+// since a search finds that out anyway.
+func isDeadState(level *Level) bool {
+	return len(deadStateReasons(level, false)) > 0
+}
+
+func deadStateReasons(level *Level, explain bool) []Diagnostic {
+	if levelIsWon(level) {
+		return nil
+	}
+
+	var reasons []Diagnostic
+	var foods []*Food
+	var snakes []*Snake
+	hasInverters, hasCrates, hasFusedSnakes, hasCellularAutomata := false, false, false, false
+	for _, entity := range level.Entities {
+		switch e := entity.(type) {
+		case *Food:
+			foods = append(foods, e)
+		case *Snake:
+			snakes = append(snakes, e)
+			hasFusedSnakes = hasFusedSnakes || len(e.FusedSnakeIDs) > 0
+		case *Inverter:
+			hasInverters = true
+		case *Crate:
+			hasCrates = true
+		case *CellularAutomata:
+			hasCellularAutomata = true
+		}
+	}
+
+	// Without inverters, snakes never change layer, and the grid never changes.
+	// Without crates, food never moves.
+	// Fused snakes can be dragged where they couldn't move on their own, so they're not considered.
+	// Cellular automata spread after every move, so what's solid now says little about what will be.
+	checkReachability := !hasInverters && !hasCrates && !hasFusedSnakes && !hasCellularAutomata
+	// Computed as needed, since the first snake checked can usually reach the food.
+	regions := map[*Snake][]bool{}
+
+	for _, food := range foods {
+		eligible := false
+		reachable := false
+		for _, snake := range snakes {
+			if len(snake.Segments) == 0 || (!hasInverters && !layersCollide(food.Layer, snake.Layer)) {
+				continue
+			}
+			eligible = true
+			if !checkReachability {
+				reachable = true
+				break
+			}
+			region, ok := regions[snake]
+			if !ok {
+				region = reachableRegion(snake, level)
+				regions[snake] = region
+			}
+			if region == nil || (withinLevel(food.Position, level) && region[food.Position.Y*level.Info.Width+food.Position.X]) {
+				reachable = true
+				break
+			}
+		}
+		switch {
+		case !eligible:
+			reasons = append(reasons, diagnosticAt(food.Position, indexOfEntity(food, level), "no snake is on a layer that can eat this food"))
+		case !reachable:
+			reasons = append(reasons, diagnosticAt(food.Position, indexOfEntity(food, level), "no snake that can eat this food can reach it"))
+		default:
+			continue
+		}
+		if !explain {
+			return reasons
+		}
+	}
+
+	if explain && len(getAllPossibleMoves(level)) == 0 {
+		reasons = append(reasons, Diagnostic{EntityIndex: -1, Message: "no snake can move"})
+	}
+	return reasons
+}
+
+// reachableRegion returns the tiles a snake's head could ever reach (indexed by y*width+x),
+// assuming the grid doesn't change, or nil if that can't be determined,
+// because other solid entities could act as bridges over the grid.
+func reachableRegion(snake *Snake, level *Level) []bool {
+	for _, entity := range level.Entities {
+		if entity != Entity(snake) && entity.IsSolid() && !layersCollide(entity.GetLayer(), snake.Layer) {
+			return nil
+		}
+	}
+	head := snake.Segments[0]
+	if !withinLevel(head, level) {
+		return nil
+	}
+	width := level.Info.Width
+	region := make([]bool, width*level.Info.Height)
+	region[head.Y*width+head.X] = true
+	stack := []Point{head}
+	for len(stack) > 0 {
+		point := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, direction := range CardinalDirections {
+			next := Point{X: point.X + direction.X, Y: point.Y + direction.Y}
+			if !withinLevel(next, level) || region[next.Y*width+next.X] || layersCollide(level.Grid[next.Y][next.X], snake.Layer) {
+				continue
+			}
+			region[next.Y*width+next.X] = true
+			stack = append(stack, next)
+		}
+	}
+	return region
+}
+
+// deadStateError describes why a level can't be won, for solver errors.
+func deadStateError(reasons []Diagnostic) error {
+	return fmt.Errorf("%w: %s", ErrUnsolvable, joinDiagnostics(reasons))
+}
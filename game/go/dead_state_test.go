@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsDeadStateWrongLayerFood(t *testing.T) {
+	level := &Level{
+		Info:     LevelInfo{Width: 3, Height: 1},
+		Grid:     [][]CollisionLayer{{Black, Black, Black}},
+		Entities: []Entity{&Snake{ID: "a", Layer: White, Segments: []Point{{X: 0, Y: 0}}}, &Food{Position: Point{X: 2, Y: 0}, Layer: Black}},
+	}
+	dead, reasons := IsDeadState(level)
+	if !dead {
+		t.Fatalf("Expected a white snake with only black food to be a dead state")
+	}
+	if len(reasons) != 1 || reasons[0].Position == nil || *reasons[0].Position != (Point{X: 2, Y: 0}) || !strings.Contains(reasons[0].Message, "layer") {
+		t.Errorf("Expected a reason about the food's layer at (2, 0), but got %v", reasons)
+	}
+}
+
+func TestIsDeadStateSealedIn(t *testing.T) {
+	// The white snake is walled in by white blocks, away from the white food.
+	level := &Level{
+		Info: LevelInfo{Width: 4, Height: 1},
+		Grid: [][]CollisionLayer{{Black, White, Black, Black}},
+		Entities: []Entity{
+			&Snake{ID: "a", Layer: White, Segments: []Point{{X: 0, Y: 0}}},
+			&Food{Position: Point{X: 3, Y: 0}, Layer: White},
+		},
+	}
+	dead, reasons := IsDeadState(level)
+	if !dead || len(reasons) == 0 || !strings.Contains(reasons[0].Message, "reach") {
+		t.Fatalf("Expected a sealed in snake to be a dead state, but got %v", reasons)
+	}
+
+	// A black snake could act as a bridge over the white block.
+	level.Entities = append(level.Entities, &Snake{ID: "b", Layer: Black, Segments: []Point{{X: 2, Y: 0}}})
+	if dead, reasons := IsDeadState(level); dead {
+		t.Errorf("Expected a possible bridge to prevent the state being considered dead, but got %v", reasons)
+	}
+}
+
+func TestIsDeadStateCellularAutomata(t *testing.T) {
+	// The white snake looks walled in by white blocks, but cells spread after every move,
+	// so where the snake can get to isn't judged from the current state.
+	level := &Level{
+		Info: LevelInfo{Width: 6, Height: 1},
+		Grid: [][]CollisionLayer{{Black, Black, White, Black, Black, White}},
+		Entities: []Entity{
+			&Snake{ID: "a", Layer: White, Segments: []Point{{X: 0, Y: 0}}},
+			&Food{Position: Point{X: 4, Y: 0}, Layer: White},
+			&CellularAutomata{Position: Point{X: 5, Y: 0}, Layer: White},
+		},
+	}
+	if dead, reasons := IsDeadState(level); dead {
+		t.Errorf("Expected reachability not to be checked with cellular automata, but got %v", reasons)
+	}
+}
+
+func TestCampaignLevelsAreNotDead(t *testing.T) {
+	levels, err := getLevels()
+	if err != nil {
+		t.Fatalf("Failed to list levels: %v", err)
+	}
+	for _, entry := range levels {
+		level, err := LoadLevel(entry.LevelId)
+		if err != nil {
+			t.Fatalf("Failed to load level %s: %v", entry.LevelId, err)
+		}
+		if dead, reasons := IsDeadState(level); dead {
+			t.Errorf("Level %s is considered dead: %v", entry.LevelId, reasons)
+		}
+	}
+}
+
+func TestSolutionStatesAreNotDead(t *testing.T) {
+	for _, playthroughId := range []string{
+		"levels/easy/002-switching-snakes-playthrough.json",
+		"levels/sketches/inverter-first-real-puzzle-playthrough.json",
+		"levels/tests/crate-test-playthrough.json",
+	} {
+		states, _, err := LoadPlaythrough(playthroughId)
+		if err != nil {
+			t.Fatalf("Failed to load playthrough: %v", err)
+		}
+		for i, state := range states {
+			if dead, reasons := IsDeadState(state); dead {
+				t.Errorf("%s: state %d of a winning playthrough is considered dead: %v", playthroughId, i, reasons)
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+)
+
+type EvolveOptions struct {
+	// Weights of metrics for the score to maximize. Defaults to solutionLength alone.
+	Weights    map[string]float64
+	Iterations int // number of mutants to try
+	// Mutations is the number of mutations applied to make each mutant, defaulting to one.
+	// More can get across valleys, where a single mutation would make the level worse or unsolvable.
+	Mutations int
+	// SolverMaxStates limits solving each mutant (0 for no limit). If the limit is reached,
+	// the parent's solution is tried on the mutant instead, so hard levels can still be evolved,
+	// but then metrics are measured on a solution that may not be a shortest one.
+	SolverMaxStates int
+	Progress        func(EvolveStep) // called for each improvement, if not nil
+}
+
+type EvolveStep struct {
+	Iteration int
+	Mutations []string // descriptions of the mutations that made this improvement
+	Score     float64
+	Optimal   bool // whether the solution is known to be a shortest one
+}
+
+type EvolveResult struct {
+	Level    *Level
+	Solution []MoveInput
+	Score    float64
+	Metrics  map[string]float64
+	Optimal  bool         // whether the solution is known to be a shortest one
+	Steps    []EvolveStep // improvements made, in order
+	Tried    int          // mutants tried, not counting repeats
+	Rejected int          // mutants that were invalid, unsolvable, or too hard to solve
+}
+
+// Shortcuts in a parent's solution are searched for within these limits, when a mutant is too hard to solve.
+var evolveSimplifyOptions = SimplifyOptions{MaxDepth: 3, MaxStates: 500}
+
+// EvolveLevel improves a level by hill-climbing: it repeatedly mutates the best level so far,
+// and keeps the mutant if it's solvable and scores higher.
+// A solution to the level can be given, to start from a level that's too hard to solve within opts.SolverMaxStates.
+// If ctx is cancelled, it returns the best level so far along with the context's error.
+func EvolveLevel(ctx context.Context, rng *rand.Rand, level *Level, solution []MoveInput, opts EvolveOptions) (EvolveResult, error) {
+	weights := opts.Weights
+	if len(weights) == 0 {
+		weights = map[string]float64{"solutionLength": 1}
+	}
+	mutations := max(opts.Mutations, 1)
+	if levelIsWon(level) {
+		return EvolveResult{Level: level}, fmt.Errorf("the level is already won, so there's nothing to evolve")
+	}
+
+	best := EvolveResult{Level: copyLevel(level), Solution: solution}
+	if solution == nil {
+		var err error
+		best.Solution, err = Solve(level, SolveOptions{MaxStates: opts.SolverMaxStates})
+		if err != nil {
+			return best, fmt.Errorf("failed to solve the starting level: %w", err)
+		}
+		best.Optimal = true
+	} else if final, ok := applyMoveInputs(level, solution...); !ok || !levelIsWon(final) {
+		return best, fmt.Errorf("%w: the given solution doesn't win the level", ErrInvalidPlaythrough)
+	}
+	var err error
+	best.Score, err = WeightedScore(best.Level, best.Solution, weights)
+	if err != nil {
+		return best, err
+	}
+	// An evolved level can't be reproduced from the generator's seed.
+	best.Level.Info.Generator = nil
+	if best.Optimal {
+		best.Level.Info.Par = len(best.Solution)
+	}
+
+	tried := map[string]bool{string(CanonicalBytes(best.Level)): true}
+	for i := 0; i < opts.Iterations; i++ {
+		if err := ctx.Err(); err != nil {
+			best.Metrics = MeasureAll(best.Level, best.Solution)
+			return best, fmt.Errorf("evolution cancelled after %d iterations: %w", i, err)
+		}
+		mutant := copyLevel(best.Level)
+		var descriptions []string
+		for len(descriptions) < mutations {
+			descriptions = append(descriptions, mutateLevel(rng, mutant))
+		}
+		key := string(CanonicalBytes(mutant))
+		if tried[key] {
+			continue
+		}
+		tried[key] = true
+		best.Tried++
+
+		mutantSolution, optimal, ok := solveMutant(ctx, mutant, best.Solution, opts.SolverMaxStates)
+		if !ok {
+			best.Rejected++
+			continue
+		}
+		score, _ := WeightedScore(mutant, mutantSolution, weights)
+		if score <= best.Score {
+			continue
+		}
+		mutant.Info.Par = 0
+		if optimal {
+			mutant.Info.Par = len(mutantSolution)
+		}
+		step := EvolveStep{Iteration: i, Mutations: descriptions, Score: score, Optimal: optimal}
+		best.Level, best.Solution, best.Score, best.Optimal = mutant, mutantSolution, score, optimal
+		best.Steps = append(best.Steps, step)
+		if opts.Progress != nil {
+			opts.Progress(step)
+		}
+	}
+	best.Metrics = MeasureAll(best.Level, best.Solution)
+	return best, nil
+}
+
+// solveMutant finds a solution to a mutant, returning whether it's known to be a shortest one,
+// or false if it's invalid, already won, unsolvable, or too hard to solve and the parent's solution doesn't work.
+func solveMutant(ctx context.Context, mutant *Level, parentSolution []MoveInput, maxStates int) ([]MoveInput, bool, bool) {
+	if len(Validate(mutant)) > 0 || !entitiesFit(mutant) || levelIsWon(mutant) {
+		return nil, false, false
+	}
+	solution, err := Solve(mutant, SolveOptions{MaxStates: maxStates})
+	if err == nil {
+		return solution, true, true
+	}
+	if !errors.Is(err, ErrSearchLimitReached) {
+		return nil, false, false
+	}
+	final, ok := applyMoveInputs(mutant, parentSolution...)
+	if !ok || !levelIsWon(final) {
+		return nil, false, false
+	}
+	// The mutation may have opened a shortcut.
+	report, err := SimplifyPlaythrough(ctx, parentSolution, mutant, evolveSimplifyOptions)
+	if err != nil {
+		return parentSolution, false, true
+	}
+	return report.MoveInputs, false, true
+}
+
+// mutateLevel applies a random mutation to the level, returning a description of it.
+// The mutation may make the level invalid, e.g. by moving a snake into a wall; that's left to the caller to check.
+func mutateLevel(rng *rand.Rand, level *Level) string {
+	snakes := getSnakes(level)
+	var food []*Food
+	for _, entity := range level.Entities {
+		if f, ok := entity.(*Food); ok {
+			food = append(food, f)
+		}
+	}
+	direction := CardinalDirections[rng.Intn(len(CardinalDirections))]
+
+	switch rng.Intn(5) {
+	case 0:
+		x, y := rng.Intn(level.Info.Width), rng.Intn(level.Info.Height)
+		// Any other layer, since inverting would leave walls (Both) and empty cells (Neither) as they are.
+		from := level.Grid[y][x]
+		level.Grid[y][x] = (from + 1 + CollisionLayer(rng.Intn(3))) % Invalid
+		return fmt.Sprintf("changed cell (%d, %d) from layer %d to %d", x, y, from, level.Grid[y][x])
+	case 1:
+		if len(food) == 0 {
+			break
+		}
+		f := food[rng.Intn(len(food))]
+		from := f.Position
+		f.Position = Point{X: from.X + direction.X, Y: from.Y + direction.Y}
+		return fmt.Sprintf("moved food from (%d, %d) to (%d, %d)", from.X, from.Y, f.Position.X, f.Position.Y)
+	case 2:
+		snake := snakes[rng.Intn(len(snakes))]
+		tail := snake.Segments[len(snake.Segments)-1]
+		snake.Segments = append(snake.Segments, Point{X: tail.X + direction.X, Y: tail.Y + direction.Y})
+		return fmt.Sprintf("lengthened snake %s", snake.ID)
+	case 3:
+		snake := snakes[rng.Intn(len(snakes))]
+		if len(snake.Segments) <= 1 {
+			break
+		}
+		snake.Segments = snake.Segments[:len(snake.Segments)-1]
+		return fmt.Sprintf("shortened snake %s", snake.ID)
+	case 4:
+		snake := snakes[rng.Intn(len(snakes))]
+		for i, segment := range snake.Segments {
+			snake.Segments[i] = Point{X: segment.X + direction.X, Y: segment.Y + direction.Y}
+		}
+		return fmt.Sprintf("shifted snake %s by (%d, %d)", snake.ID, direction.X, direction.Y)
+	}
+	// The chosen mutation didn't apply, e.g. there's no food to move.
+	return mutateLevel(rng, level)
+}
+
+// entitiesFit returns false if a snake overlaps itself, or is on top of a wall or snake on its layer,
+// or if food is out of bounds or on top of other food. Other problems are caught by Validate.
+func entitiesFit(level *Level) bool {
+	// Entities later in the list are on top, so a snake only needs to fit on the layer below it.
+	topLayers := map[Point]CollisionLayer{}
+	var foodPositions []Point
+	for _, entity := range level.Entities {
+		switch e := entity.(type) {
+		case *Snake:
+			for i, segment := range e.Segments {
+				if !withinLevel(segment, level) || slices.Contains(e.Segments[:i], segment) {
+					return false
+				}
+				below, ok := topLayers[segment]
+				if !ok {
+					below = level.Grid[segment.Y][segment.X]
+				}
+				if layersCollide(below, e.Layer) {
+					return false
+				}
+			}
+			for _, segment := range e.Segments {
+				topLayers[segment] = e.Layer
+			}
+		case *Food:
+			if !withinLevel(e.Position, level) || slices.Contains(foodPositions, e.Position) {
+				return false
+			}
+			foodPositions = append(foodPositions, e.Position)
+		}
+	}
+	return true
+}
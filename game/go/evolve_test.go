@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestEvolveLevel(t *testing.T) {
+	config := DefaultGeneratorConfig()
+	config.Tries = 20
+	config.Solve = true
+	generated, err := GenerateLevelFromSeed(3, config)
+	if err != nil {
+		t.Fatalf("Failed to generate level: %v", err)
+	}
+	result, err := EvolveLevel(context.Background(), rand.New(rand.NewSource(2)), generated.Level, nil, EvolveOptions{Iterations: 30})
+	if err != nil {
+		t.Fatalf("Failed to evolve level: %v", err)
+	}
+	if len(result.Steps) == 0 || result.Score <= float64(len(generated.Solution)) {
+		t.Errorf("Expected the level to be improved, but got score %g from %d", result.Score, len(generated.Solution))
+	}
+	previousScore := float64(len(generated.Solution))
+	for _, step := range result.Steps {
+		if step.Score <= previousScore {
+			t.Errorf("Expected each step to improve the score, but got %g after %g", step.Score, previousScore)
+		}
+		previousScore = step.Score
+	}
+	final, ok := applyMoveInputs(result.Level, result.Solution...)
+	if !ok || !levelIsWon(final) {
+		t.Errorf("Expected the solution to win the evolved level")
+	}
+	if !result.Optimal || result.Level.Info.Par != len(result.Solution) {
+		t.Errorf("Expected a shortest solution as the par, but got par %d for %d moves", result.Level.Info.Par, len(result.Solution))
+	}
+	if result.Level.Info.Generator != nil {
+		t.Errorf("Expected the generator info to be dropped")
+	}
+}
+
+func TestEvolveHardLevelWithPlaythrough(t *testing.T) {
+	level, err := LoadLevel("levels/medium/proper-lock.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	_, moveInputs, err := LoadPlaythrough("levels/medium/proper-lock-playthrough.json")
+	if err != nil {
+		t.Fatalf("Failed to load playthrough: %v", err)
+	}
+	if _, err := EvolveLevel(context.Background(), rand.New(rand.NewSource(1)), level, nil, EvolveOptions{Iterations: 1, SolverMaxStates: 100}); err == nil {
+		t.Errorf("Expected an error for a level too hard to solve without a playthrough")
+	}
+	result, err := EvolveLevel(context.Background(), rand.New(rand.NewSource(1)), level, moveInputs, EvolveOptions{
+		Iterations:      5,
+		SolverMaxStates: 100,
+		Weights:         map[string]float64{"pinchPoints": 1},
+	})
+	if err != nil {
+		t.Fatalf("Failed to evolve level: %v", err)
+	}
+	final, ok := applyMoveInputs(result.Level, result.Solution...)
+	if !ok || !levelIsWon(final) {
+		t.Errorf("Expected the solution to win the evolved level")
+	}
+	if result.Optimal || result.Level.Info.Par != 0 {
+		t.Errorf("Expected the solution not to be known as a shortest one")
+	}
+}
+
+func TestEntitiesFit(t *testing.T) {
+	level, err := LoadLevel("levels/easy/004-ferry.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	if !entitiesFit(level) {
+		t.Fatalf("Expected the entities of a campaign level to fit")
+	}
+	for name, modify := range map[string]func(*Snake){
+		"out of bounds": func(snake *Snake) { snake.Segments[0] = Point{X: -1, Y: 0} },
+		"overlapping":   func(snake *Snake) { snake.Segments = append(snake.Segments, snake.Segments[0]) },
+		"in a wall":     func(snake *Snake) { snake.Layer = invertCollisionLayer(snake.Layer) },
+	} {
+		modified := copyLevel(level)
+		modify(getSnakes(modified)[0])
+		if entitiesFit(modified) {
+			t.Errorf("%s: expected the snake not to fit", name)
+		}
+	}
+}
+
+func TestMutateLevelAlwaysChangesIt(t *testing.T) {
+	// Walls and empty cells, which inverting a cell would leave as they are.
+	level := &Level{
+		Info: LevelInfo{Width: 3, Height: 2},
+		Grid: [][]CollisionLayer{{Both, Neither, Both}, {Neither, Both, Neither}},
+		Entities: []Entity{
+			&Snake{ID: "a", Layer: White, Segments: []Point{{X: 1, Y: 0}}},
+			&Food{Position: Point{X: 0, Y: 1}, Layer: White},
+		},
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		mutant := copyLevel(level)
+		description := mutateLevel(rng, mutant)
+		if Equal(mutant, level) {
+			t.Errorf("Expected the mutation %q to change the level", description)
+		}
+	}
+}
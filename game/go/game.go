@@ -19,6 +19,11 @@ type Game struct {
 	activeSnake     *Snake
 	blinkSnake      bool
 	blinkEncumbered bool
+	moves           int // moves taken since the level was (re)started
+	// deadReasons caches IsDeadState for the level, since it's too slow to check on every frame.
+	// It's only valid if deadStateChecked, which must be cleared whenever the level changes.
+	deadStateChecked bool
+	deadReasons      []Diagnostic
 }
 
 func activateSomeSnake(game *Game) {
@@ -53,6 +58,9 @@ func LoadLevel(levelId string) (*Level, error) {
 	if len(level.Entities) == 0 {
 		return nil, fmt.Errorf("level %s has no entities", levelId)
 	}
+	if diagnostics := Validate(level); len(diagnostics) > 0 {
+		return nil, fmt.Errorf("failed to load level %s: %w", levelId, &ValidationError{Diagnostics: diagnostics})
+	}
 	return level, nil
 }
 
@@ -87,6 +95,8 @@ func loadNextLevel(g *Game, backwards bool) {
 		panic(err)
 	}
 	g.level = level
+	g.moves = 0
+	g.deadStateChecked = false
 	activateSomeSnake(g)
 }
 
@@ -151,6 +161,8 @@ func move(direction Point, g *Game, undos *[]*Game, redos *[]*Game) {
 	if move.Valid {
 		undoable(g, undos, redos)
 		TakeMove(move, g.level)
+		g.moves++
+		g.deadStateChecked = false
 		if levelIsWon(g.level) {
 			loadNextLevel(g, false)
 		}
@@ -160,6 +172,15 @@ func move(direction Point, g *Game, undos *[]*Game, redos *[]*Game) {
 	}
 }
 
+// deadState returns whether the level can no longer be won, and why, checking only after the level changes.
+func deadState(g *Game) (bool, []Diagnostic) {
+	if !g.deadStateChecked {
+		_, g.deadReasons = IsDeadState(g.level)
+		g.deadStateChecked = true
+	}
+	return len(g.deadReasons) > 0, g.deadReasons
+}
+
 func cycleActiveSnake(g *Game) {
 	if g.activeSnake == nil {
 		return
@@ -233,6 +254,8 @@ func mainGameLoop(ascii bool, levelId string) {
 						// return
 					} else {
 						g.level = level
+						g.moves = 0
+						g.deadStateChecked = false
 						activateSomeSnake(g)
 					}
 				case ev.Ch == 'n':
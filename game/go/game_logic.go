@@ -28,14 +28,12 @@ func AnalyzeMoveRelative(snake *Snake, deltaX, deltaY int, level *Level) Move {
 
 	encumbered := false
 	for _, hit := range hitsAllAlong {
-		// if hit.Entity.Solid() &&
-		// 	hit.Entity != snake &&
-		// 	indexOfEntity(hit, level.Entity) > indexOfEntity(snake, level) &&
-		// 	!(hit.EntityIsSnake() && snake.FusedSnakeIds.Contains(hit.EntityID())) {
+		hitSnake, hitIsSnake := hit.Entity.(*Snake)
 		encumbered = hit.Entity != nil &&
 			hit.Entity.IsSolid() &&
 			hit.Entity != snake &&
-			indexOfEntity(hit.Entity, level) > indexOfEntity(snake, level)
+			indexOfEntity(hit.Entity, level) > indexOfEntity(snake, level) &&
+			!(hitIsSnake && slices.Contains(snake.FusedSnakeIDs, hitSnake.ID))
 		if encumbered {
 			break
 		}
@@ -62,36 +60,36 @@ func AnalyzeMoveRelative(snake *Snake, deltaX, deltaY int, level *Level) Move {
 		// If moves are analyzed by checking for collisions within a whole game board,
 		// it could share some code. Theoretically.
 
-	// // Push objects
-	// var entitiesToPush []entity.Entity
-	// {
-	// 	hit := FindFirstSolidEntity(hitsAhead)
-	// 	// TODO: try pushing other snakes too (or save that for when splitting snakes; only push the tail / "dead" half)
-	// 	// TODO: recursively push crates
-	// 	if crateEntity, ok := hit.(*crate.Crate); ok {
-	// 		newTile := Point{
-	// 			X:      crateEntity.X + deltaX,
-	// 			Y:      crateEntity.Y + deltaY,
-	// 			Width:  crateEntity.Width,
-	// 			Height: crateEntity.Height,
-	// 		}
-	// 		hitsAheadCrate := HitTestAllEntities(newTile.X, newTile.Y, HitTestOptions{IgnoreTailOfSnake: snake})
-	// 		if WithinLevel(newTile) &&
-	// 			LayersCollide(crateEntity.Layer, head.Layer) &&
-	// 			!LayersCollide(TopLayer(hitsAheadCrate), crateEntity.Layer) {
-	// 			entitiesToPush = append(entitiesToPush, crateEntity)
-	// 			for _, h := range hitsAhead {
-	// 				if _, ok := h.Entity.(*collectable.Collectable); ok {
-	// 					entitiesToPush = append(entitiesToPush, h.Entity)
-	// 					break
-	// 				}
-	// 			}
-	// 		}
-	// 	}
-	// }
+	// Push objects
+	var entitiesToPush []Entity
+	{
+		hit := firstSolidHit(hitsAhead)
+		// TODO: try pushing other snakes too (or save that for when splitting snakes; only push the tail / "dead" half)
+		// TODO: recursively push crates
+		if hit != nil {
+			if crate, ok := hit.Entity.(*Crate); ok {
+				// Check if the crate can be pushed
+				newTile := Point{X: crate.Position.X + deltaX, Y: crate.Position.Y + deltaY}
+				hitsAheadCrate := hitTestAllEntities(newTile.X, newTile.Y, level, HitTestOptions{IgnoreTailOfSnake: snake})
+				if withinLevel(newTile, level) &&
+					layersCollide(crate.Layer, snake.Layer) &&
+					!layersCollide(topLayer(hitsAheadCrate), crate.Layer) {
+					entitiesToPush = append(entitiesToPush, crate)
+					for _, h := range hitsAhead {
+						if _, ok := h.Entity.(Collectable); ok {
+							entitiesToPush = append(entitiesToPush, h.Entity)
+							break
+						}
+					}
+				}
+			}
+		}
+	}
 
 	// Ignore pushed objects as obstacles
-	// hitsAhead = FilterHitsExcludingEntities(hitsAhead, entitiesToPush)
+	hitsAhead = slices.DeleteFunc(hitsAhead, func(hit Hit) bool {
+		return hit.Entity != nil && slices.Contains(entitiesToPush, hit.Entity)
+	})
 
 	return Move{
 		// SnakeId:   s.ID,
@@ -102,11 +100,11 @@ func AnalyzeMoveRelative(snake *Snake, deltaX, deltaY int, level *Level) Move {
 			!movingBackwards &&
 			!encumbered &&
 			!layersCollide(topLayer(hitsAhead), snake.Layer),
-		Encumbered:    encumbered,
-		To:            Point{X: x, Y: y},
-		Delta:         Point{X: deltaX, Y: deltaY},
-		EntitiesThere: hitsToEntities(hitsAhead),
-		// EntitiesToPush: entitiesToPush,
+		Encumbered:     encumbered,
+		To:             Point{X: x, Y: y},
+		Delta:          Point{X: deltaX, Y: deltaY},
+		EntitiesThere:  hitsToEntities(hitsAhead),
+		EntitiesToPush: entitiesToPush,
 	}
 }
 
@@ -151,196 +149,197 @@ func TakeMove(m Move, level *Level) {
 		}
 	}
 
+	// Push objects
 	// if len(m.EntitiesToPush) > 0 {
 	// 	audio.PlaySound("pushCrate", audio.Options{
 	// 		PlaybackRate: rand.Float64()*0.1 + 0.95,
 	// 		Volume:       0.3,
 	// 	})
 	// }
+	for _, e := range m.EntitiesToPush {
+		translateEntity(e, m.Delta.X, m.Delta.Y)
+		index := indexOfEntity(e, level)
+		level.Entities = append(slices.Delete(level.Entities, index, index+1), e)
+	}
+	// Ensure collectables are on top of crates, so that you can scoop up collectables inside crates to push them around.
+	if len(m.EntitiesToPush) > 0 {
+		sortEntities(level)
+	}
 
-	// for _, e := range m.EntitiesToPush {
-	// 	TranslateEntity(e, m.Delta.X, m.Delta.Y)
-	// 	game.RemoveEntity(e)
-	// 	game.AddEntityToTop(e)
-	// }
-
-	// if len(m.EntitiesToPush) > 0 {
-	// 	game.SortEntities()
-	// }
-
+	// Eat collectables
 	for _, e := range m.EntitiesThere {
-		if c, ok := e.(*Food); ok { // TODO: collectables in general
-			if layersCollide(c.Layer, s.Layer) { // TODO: && !entityInSlice(c, m.EntitiesToPush) {
+		if c, ok := e.(Collectable); ok {
+			if layersCollide(c.GetLayer(), s.Layer) && !slices.Contains(m.EntitiesToPush, e) {
 				index := indexOfEntity(c, level)
 				if index < 0 {
 					continue // maybe
 				}
 				level.Entities = slices.Delete(level.Entities, index, index+1)
-				// if _, isFood := c.(*food.Food); isFood {
-				s.GrowOnNextMove = true
 				// if !game.CheckLevelWon() {
 				// 	audio.PlayMelodicSound("eat", s.NextMelodyIndex())
 				// }
-				// } else if _, isInverter := c.(*inverter.Inverter); isInverter {
-				// 	InvertSnake(s)
-				// }
+				c.Collect(s, level)
 			}
 		}
 	}
 
-	// for id := range s.FusedSnakeIds {
-	// 	if fs, ok := game.FindSnakeByID(id); ok {
-	// 		DragSnake(fs, len(fs.Segments)-1, s.Segments[len(s.Segments)-1])
-	// 	}
-	// }
+	// Pull fused snakes
+	for _, id := range s.FusedSnakeIDs {
+		// Invalid FusedSnakeIDs are cleaned up when serializing.
+		// It's easier than trying to actively clear relationships when entities are deleted.
+		for _, fusedSnake := range getSnakes(level) {
+			if fusedSnake.ID == id && fusedSnake != s {
+				// Assuming snakes are fused at the tails.
+				DragSnake(fusedSnake, len(fusedSnake.Segments)-1, s.Segments[len(s.Segments)-1])
+			}
+		}
+	}
 
-	// UpdateCellularAutomata()
+	UpdateCellularAutomata(level)
 	// s.AnimateMove(m, originalTailPos)
 }
 
-// // TODO: DRY, copied from function `drag` in level-editor.ts
-// func DragSnake(dragging *Snake, index int, to Point) {
-// 	segment := dragging.Segments[index]
-// 	if segment.X != to.X || segment.Y != to.Y {
-// 		from := Point{X: segment.X, Y: segment.Y}
-// 		points := LineNoDiagonals(from, to)
-// 		for _, point := range points[1:] {
-// 			for i := len(dragging.Segments) - 1; i > index; i-- {
-// 				lead(dragging.Segments[i-1], dragging.Segments[i])
-// 			}
-// 			for i := 0; i < index; i++ {
-// 				lead(dragging.Segments[i+1], dragging.Segments[i])
-// 			}
-// 			segment.X = point.X
-// 			segment.Y = point.Y
-// 			// if snake.DEBUG_SNAKE_DRAGGING {
-// 			//   draw()
-// 			// }
-// 		}
-// 	}
-// }
-
-// // TODO: DRY, copied from function `lead` in level-editor.ts
-// func lead(leader, follower *snake.Segment) {
-// 	follower.X = leader.X
-// 	follower.Y = leader.Y
-// 	follower.Width = leader.Width
-// 	follower.Height = leader.Height
-// 	// if snake.DEBUG_SNAKE_DRAGGING {
-// 	//   draw()
-// 	// }
-// }
-
-/*
-func InvertSnake(s *Snake) {
-  // See also: `invert` function in level-editor.ts
-	handledEntities := make(map[entity.Entity]bool)
-	handledPositions := make(map[string]bool)
-
-	var handleEntity func(entity.Entity)
+// TODO: DRY, copied from function `drag` in level-editor.ts
+func DragSnake(dragging *Snake, index int, to Point) {
+	segment := &dragging.Segments[index]
+	if *segment == to {
+		return
+	}
+	// Avoids diagonals and segments longer than 1 tile
+	// Skip the first point, since it's the same as the segment's current position
+	for _, point := range lineNoDiagonals(*segment, to)[1:] {
+		for i := len(dragging.Segments) - 1; i > index; i-- {
+			dragging.Segments[i] = dragging.Segments[i-1]
+		}
+		for i := 0; i < index; i++ {
+			dragging.Segments[i] = dragging.Segments[i+1]
+		}
+		*segment = point
+	}
+}
+
+// See also: `invert` function in level-editor.ts
+func InvertSnake(snake *Snake, level *Level) {
+	handledEntities := make(map[Entity]bool)
+	handledPositions := make(map[Point]bool)
+
+	var handleEntity func(Entity)
 	var handlePosition func(int, int)
 
-	handleEntity = func(e entity.Entity) {
-		if handledEntities[e] {
+	handleEntity = func(entity Entity) {
+		if handledEntities[entity] {
 			return
 		}
-		handledEntities[e] = true
-		if re, ok := e.(*rectangularentity.RectangularEntity); ok {
-			re.Layer = InvertCollisionLayer(re.Layer)
-		} else if snk, ok := e.(*Snake); ok {
-			for _, seg := range snk.Segments {
-				handlePosition(seg.X, seg.Y)
-				seg.Layer = InvertCollisionLayer(seg.Layer)
+		handledEntities[entity] = true
+		switch e := entity.(type) {
+		case *Food:
+			e.Layer = invertCollisionLayer(e.Layer)
+		case *Inverter:
+			e.Layer = invertCollisionLayer(e.Layer)
+		case *Crate:
+			e.Layer = invertCollisionLayer(e.Layer)
+		case *CellularAutomata:
+			e.Layer = invertCollisionLayer(e.Layer)
+		case *Snake:
+			for _, segment := range e.Segments {
+				handlePosition(segment.X, segment.Y)
 			}
+			e.Layer = invertCollisionLayer(e.Layer)
 		}
 	}
 
 	handlePosition = func(x, y int) {
-		key := fmt.Sprintf("%d,%d", x, y)
-		if handledPositions[key] {
+		if handledPositions[Point{X: x, Y: y}] {
 			return
 		}
-		handledPositions[key] = true
-		for _, hit := range HitTestAllEntities(x, y) {
-			handleEntity(hit.Entity)
+		handledPositions[Point{X: x, Y: y}] = true
+		for _, hit := range hitTestAllEntities(x, y, level, HitTestOptions{}) {
+			if hit.Entity == nil {
+				// In the TS version, blocks are entities, and implicit black is replaced with a white block.
+				// Here, implicit black is just Black in the grid, so inverting it covers both cases.
+				level.Grid[y][x] = invertCollisionLayer(level.Grid[y][x])
+			} else {
+				handleEntity(hit.Entity)
+			}
 		}
 	}
 
-	handleEntity(s)
-
-	for pos := range handledPositions {
-		var x, y int
-		fmt.Sscanf(pos, "%d,%d", &x, &y)
-		hits := HitTestAllEntities(x, y)
-		if !AnyBlock(hits) {
-			block := &block.Block{
-				X:     x,
-				Y:     y,
-				Layer: types.CollisionLayerWhite,
-			}
-			game.AddEntityToBottom(block)
+	handleEntity(snake)
+}
+
+// onlyBlocksOrCellularAutomata returns true if there's nothing but the grid and cellular automata in the hits.
+func onlyBlocksOrCellularAutomata(hits []Hit) bool {
+	for _, hit := range hits {
+		if _, isCellularAutomata := hit.Entity.(*CellularAutomata); hit.Entity != nil && !isCellularAutomata {
+			return false
 		}
 	}
+	return true
 }
 
-func UpdateCellularAutomata() {
-	occupied := map[string]*cellularautomata.CellularAutomata{}
-	for _, e := range game.Entities {
-		if ca, ok := e.(*cellularautomata.CellularAutomata); ok {
-			key := fmt.Sprintf("%d,%d", ca.X, ca.Y)
-			occupied[key] = ca
+// UpdateCellularAutomata steps all cellular automata in the level.
+// Cells spread to neighboring tiles where they contrast with what's underneath,
+// as long as there's nothing but the grid (and other cells) there.
+func UpdateCellularAutomata(level *Level) {
+	occupiedTiles := map[Point]*CellularAutomata{}
+	for _, entity := range level.Entities {
+		if cell, ok := entity.(*CellularAutomata); ok {
+			occupiedTiles[cell.Position] = cell
 		}
 	}
+	if len(occupiedTiles) == 0 {
+		return
+	}
 
-	newOccupied := map[string]bool{}
-	for y := 0; y < game.LevelInfo.Height; y++ {
-		for x := 0; x < game.LevelInfo.Width; x++ {
-			key := fmt.Sprintf("%d,%d", x, y)
-			neighbors := []string{
-				fmt.Sprintf("%d,%d", x, y-1),
-				fmt.Sprintf("%d,%d", x+1, y),
-				fmt.Sprintf("%d,%d", x, y+1),
-				fmt.Sprintf("%d,%d", x-1, y),
-			}
-
-			count := 0
-			for _, n := range neighbors {
-				if occupied[n] != nil {
-					layer := TopLayer(FilterHitsExcludingType(HitTestAllEntities(x, y), &cellularautomata.CellularAutomata{}))
-					if occupied[n].Layer == InvertCollisionLayer(layer) {
-						count++
-					}
+	// Tiles are visited in a fixed order so that new cells are added deterministically.
+	var newOccupiedTiles []Point
+	newOccupied := map[Point]bool{}
+	for y := 0; y < level.Info.Height; y++ {
+		for x := 0; x < level.Info.Width; x++ {
+			tile := Point{X: x, Y: y}
+			hits := hitTestAllEntities(x, y, level, HitTestOptions{})
+			hitsExcludingCells := slices.DeleteFunc(slices.Clone(hits), func(hit Hit) bool {
+				_, isCellularAutomata := hit.Entity.(*CellularAutomata)
+				return isCellularAutomata
+			})
+			contrastingLayer := invertCollisionLayer(topLayer(hitsExcludingCells))
+			neighborCount := 0
+			for _, direction := range CardinalDirections {
+				neighbor := occupiedTiles[Point{X: x + direction.X, Y: y + direction.Y}]
+				if neighbor != nil && neighbor.Layer == contrastingLayer {
+					neighborCount++
 				}
 			}
 
-			if count >= 1 || occupied[key] != nil {
-				if AllEntitiesAreBlocksOrCA(HitTestAllEntities(x, y)) {
-					newOccupied[key] = true
+			// Conway's Game of Life rules would require 8 neighbors.
+			// Simplest rule: just grow (designed for 4 neighbors):
+			if neighborCount >= 1 || occupiedTiles[tile] != nil {
+				if onlyBlocksOrCellularAutomata(hits) {
+					newOccupiedTiles = append(newOccupiedTiles, tile)
+					newOccupied[tile] = true
 				}
 			}
 		}
 	}
 
-	for i := len(game.Entities) - 1; i >= 0; i-- {
-		if ca, ok := game.Entities[i].(*cellularautomata.CellularAutomata); ok {
-			key := fmt.Sprintf("%d,%d", ca.X, ca.Y)
-			if !newOccupied[key] && AllEntitiesAreBlocksOrCA(HitTestAllEntities(ca.X, ca.Y)) {
-				game.Entities = append(game.Entities[:i], game.Entities[i+1:]...)
+	for i := len(level.Entities) - 1; i >= 0; i-- {
+		if cell, ok := level.Entities[i].(*CellularAutomata); ok {
+			if newOccupied[cell.Position] {
+				continue
+			}
+			if onlyBlocksOrCellularAutomata(hitTestAllEntities(cell.Position.X, cell.Position.Y, level, HitTestOptions{})) {
+				level.Entities = slices.Delete(level.Entities, i, i+1)
 			}
 		}
 	}
 
-	for key := range newOccupied {
-		if occupied[key] == nil {
-			var x, y int
-			fmt.Sscanf(key, "%d,%d", &x, &y)
-			cell := &cellularautomata.CellularAutomata{
-				X:     x,
-				Y:     y,
-				Layer: InvertCollisionLayer(TopLayer(HitTestAllEntities(x, y))),
-			}
-			game.Entities = append(game.Entities, cell)
+	for _, tile := range newOccupiedTiles {
+		if occupiedTiles[tile] != nil {
+			continue
 		}
+		level.Entities = append(level.Entities, &CellularAutomata{
+			Position: tile,
+			Layer:    invertCollisionLayer(topLayer(hitTestAllEntities(tile.X, tile.Y, level, HitTestOptions{}))),
+		})
 	}
 }
-*/
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// replayPlaythrough checks that the moves in a playthrough are valid in the Go engine,
+// and lead to the same states as recorded.
+func replayPlaythrough(t *testing.T, playthroughId string) *Level {
+	t.Helper()
+	states, moveInputs, err := LoadPlaythrough(playthroughId)
+	if err != nil {
+		t.Fatalf("Failed to load playthrough: %v", err)
+	}
+	level := copyLevel(states[0])
+	for i, input := range moveInputs {
+		move := AnalyzeMoveRelative(getSnakeByID(input.SnakeID, level), input.Direction.X, input.Direction.Y, level)
+		if !move.Valid {
+			t.Fatalf("Move %d is invalid: snake ID '%s', direction (%d, %d)", i, input.SnakeID, input.Direction.X, input.Direction.Y)
+		}
+		TakeMove(move, level)
+		if !Equal(level, states[i+1]) {
+			t.Fatalf("State after move %d doesn't match the playthrough", i)
+		}
+	}
+	return level
+}
+
+func TestCratePlaythrough(t *testing.T) {
+	level := replayPlaythrough(t, "levels/tests/crate-test-playthrough.json")
+	if !levelIsWon(level) {
+		t.Errorf("Expected the level to be won")
+	}
+}
+
+func TestMoveRightShouldNotPushCrate(t *testing.T) {
+	level, err := LoadLevel("levels/tests/move-right-should-not-push-crate.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	snake := getSnakes(level)[0]
+	move := AnalyzeMoveRelative(snake, Right.X, Right.Y, level)
+	if len(move.EntitiesToPush) != 0 {
+		t.Errorf("Expected no entities to push, but got %v", move.EntitiesToPush)
+	}
+}
+
+func TestSnakeShouldBeAbleToPushCrateDownToItsTail(t *testing.T) {
+	level, err := LoadLevel("levels/tests/snake-should-be-able-to-push-crate-down-to-its-tail.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	snake := getSnakes(level)[0]
+	move := AnalyzeMoveRelative(snake, Down.X, Down.Y, level)
+	if !move.Valid {
+		t.Fatalf("Expected moving down to be valid")
+	}
+	// The crate has food inside it, which should be pushed along with it.
+	if len(move.EntitiesToPush) != 2 {
+		t.Fatalf("Expected to push a crate and food, but got %v", move.EntitiesToPush)
+	}
+	crate := move.EntitiesToPush[0].(*Crate)
+	food := move.EntitiesToPush[1].(*Food)
+	before := crate.Position
+	TakeMove(move, level)
+	if crate.Position != (Point{X: before.X, Y: before.Y + 1}) {
+		t.Errorf("Expected crate to move from %v down one tile, but it's at %v", before, crate.Position)
+	}
+	if food.Position != crate.Position {
+		t.Errorf("Expected food to move with the crate to %v, but it's at %v", crate.Position, food.Position)
+	}
+	if indexOfEntity(food, level) < 0 {
+		t.Errorf("Expected pushed food not to be eaten")
+	}
+}
+
+func TestInverterPlaythrough(t *testing.T) {
+	level := replayPlaythrough(t, "levels/sketches/inverter-first-real-puzzle-playthrough.json")
+	if !levelIsWon(level) {
+		t.Errorf("Expected the level to be won")
+	}
+}
+
+func TestInverterInvertsCellularAutomata(t *testing.T) {
+	cell := &CellularAutomata{Position: Point{X: 1, Y: 0}, Layer: Black}
+	level := &Level{
+		Info: LevelInfo{Width: 4, Height: 1},
+		Grid: [][]CollisionLayer{{Black, Black, Black, Black}},
+		Entities: []Entity{
+			cell,
+			&Snake{ID: "a", Layer: White, Segments: []Point{{X: 2, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 0}}},
+			&Inverter{Position: Point{X: 3, Y: 0}, Layer: White},
+		},
+	}
+	snake := getSnakeByID("a", level)
+	move := AnalyzeMoveRelative(snake, Right.X, Right.Y, level)
+	if !move.Valid {
+		t.Fatalf("Expected moving onto the inverter to be valid")
+	}
+	TakeMove(move, level)
+	if snake.Layer != Black {
+		t.Fatalf("Expected the snake to be inverted")
+	}
+	// The cell is under the snake, so it should be inverted along with the grid there.
+	if indexOfEntity(cell, level) < 0 {
+		t.Fatalf("Expected the cell under the snake to remain")
+	}
+	if cell.Layer != White {
+		t.Errorf("Expected the cell under the snake to be inverted to white, but it's %v", cell.Layer)
+	}
+}
+
+func TestCellularAutomataPlaythrough(t *testing.T) {
+	level := replayPlaythrough(t, "levels/sketches/grower-slower-playthrough.json")
+	if !levelIsWon(level) {
+		t.Errorf("Expected the level to be won")
+	}
+}
+
+func TestFusedSnakeIsDraggedByTail(t *testing.T) {
+	level, err := LoadLevel("levels/sketches/fused-snakes.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	snake := getSnakeByID("d24822bc-69e5-4533-92f5-0c1929f65efc", level)
+	fusedSnake := getSnakeByID("6eb41157-b9cd-459d-8df6-a954e9a12d60", level)
+	// The fused snake overlaps this snake's tail, which shouldn't count as being encumbered.
+	move := AnalyzeMoveRelative(snake, Up.X, Up.Y, level)
+	if !move.Valid {
+		t.Fatalf("Expected moving up to be valid")
+	}
+	TakeMove(move, level)
+	tail := snake.Segments[len(snake.Segments)-1]
+	fusedTail := fusedSnake.Segments[len(fusedSnake.Segments)-1]
+	if fusedTail != tail {
+		t.Errorf("Expected fused snake's tail to follow to %v, but it's at %v", tail, fusedTail)
+	}
+	expected := []Point{{X: 5, Y: 14}, {X: 4, Y: 14}, {X: 4, Y: 13}, {X: 5, Y: 13}, {X: 5, Y: 12}}
+	if !slices.Equal(fusedSnake.Segments, expected) {
+		t.Errorf("Expected fused snake to be dragged by its tail, but got %v", fusedSnake.Segments)
+	}
+}
+
+func TestLineNoDiagonals(t *testing.T) {
+	points := lineNoDiagonals(Point{X: 0, Y: 0}, Point{X: 2, Y: 1})
+	if len(points) != 4 || points[0] != (Point{X: 0, Y: 0}) || points[3] != (Point{X: 2, Y: 1}) {
+		t.Fatalf("Unexpected line %v", points)
+	}
+	for i := 1; i < len(points); i++ {
+		if abs(points[i].X-points[i-1].X)+abs(points[i].Y-points[i-1].Y) != 1 {
+			t.Errorf("Expected orthogonal steps, but got %v", points)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidGeneratorConfig = errors.New("invalid generator config")
+
+// GeneratorConfig controls the shape of generated levels.
+// Ranges are inclusive.
+type GeneratorConfig struct {
+	MinWidth     int     `json:"minWidth"`
+	MaxWidth     int     `json:"maxWidth"`
+	MinHeight    int     `json:"minHeight"`
+	MaxHeight    int     `json:"maxHeight"`
+	BlockDensity float64 `json:"blockDensity"` // chance of each tile being white
+	// FoodChance is the chance of leaving food behind each time a snake moves backwards,
+	// shrinking it, while simulating in reverse.
+	FoodChance float64 `json:"foodChance"`
+	MinSnakes  int     `json:"minSnakes"`
+	MaxSnakes  int     `json:"maxSnakes"`
+	// Snake lengths are targets before simulating in reverse, limited by the space available.
+	MinSnakeLength int `json:"minSnakeLength"`
+	MaxSnakeLength int `json:"maxSnakeLength"`
+	Tries          int `json:"tries"` // number of candidate levels to generate, keeping the best one
+	Steps          int `json:"steps"` // number of reverse moves to attempt for each candidate
+	// Solve runs the solver on each candidate, dropping those that are unsolvable or trivial,
+	// and scoring the rest by the length of a shortest solution, instead of the amount of food.
+	Solve             bool `json:"solve"`
+	MinSolutionLength int  `json:"minSolutionLength"` // shorter solutions are considered trivial
+	SolverMaxStates   int  `json:"solverMaxStates"`   // candidates that take more states to solve are dropped, or 0 for no limit
+	// MetricWeights scores candidates by a weighted sum of Metrics, by name, instead of solution length.
+	// Negative weights are allowed, to penalize a metric. Requires Solve.
+	MetricWeights map[string]float64 `json:"metricWeights,omitempty"`
+}
+
+// I think smaller levels should be statistically more likely to generate
+// "puzzle"-like levels rather than meaningless traversal.
+func DefaultGeneratorConfig() GeneratorConfig {
+	return GeneratorConfig{
+		MinWidth:       2,
+		MaxWidth:       6,
+		MinHeight:      2,
+		MaxHeight:      6,
+		BlockDensity:   0.3,
+		FoodChance:     0.9,
+		MinSnakes:      1,
+		MaxSnakes:      3,
+		MinSnakeLength: 2,
+		MaxSnakeLength: 11,
+		Tries:          200,
+		Steps:          10000,
+
+		Solve:             false,
+		MinSolutionLength: 4,
+		SolverMaxStates:   20000,
+	}
+}
+
+// Validate checks for settings that can never generate a level.
+func (config GeneratorConfig) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	check(config.MinWidth >= 1, "minWidth must be at least 1")
+	check(config.MinHeight >= 1, "minHeight must be at least 1")
+	check(config.MaxWidth >= config.MinWidth, "maxWidth (%d) must be at least minWidth (%d)", config.MaxWidth, config.MinWidth)
+	check(config.MaxHeight >= config.MinHeight, "maxHeight (%d) must be at least minHeight (%d)", config.MaxHeight, config.MinHeight)
+	check(config.BlockDensity >= 0 && config.BlockDensity <= 1, "blockDensity must be between 0 and 1")
+	check(config.FoodChance > 0 && config.FoodChance <= 1, "foodChance must be more than 0 and at most 1, since levels need food")
+	check(config.MinSnakes >= 1, "minSnakes must be at least 1")
+	check(config.MaxSnakes >= config.MinSnakes, "maxSnakes (%d) must be at least minSnakes (%d)", config.MaxSnakes, config.MinSnakes)
+	check(config.MinSnakeLength >= 1, "minSnakeLength must be at least 1")
+	check(config.MaxSnakeLength >= config.MinSnakeLength, "maxSnakeLength (%d) must be at least minSnakeLength (%d)", config.MaxSnakeLength, config.MinSnakeLength)
+	// Snakes shrink as they move backwards, leaving food behind, so a snake of length 1 can't make any food.
+	check(config.MaxSnakeLength >= 2, "maxSnakeLength must be at least 2, for snakes to have food to eat")
+	// A snake can't overlap itself.
+	check(config.MinSnakeLength <= config.MaxWidth*config.MaxHeight, "minSnakeLength (%d) can't fit in a level of at most %dx%d", config.MinSnakeLength, config.MaxWidth, config.MaxHeight)
+	check(config.Tries >= 1, "tries must be at least 1")
+	check(config.Steps >= 1, "steps must be at least 1")
+	check(config.SolverMaxStates >= 0, "solverMaxStates must not be negative")
+	check(len(config.MetricWeights) == 0 || config.Solve, "metricWeights requires solve, since metrics are measured on the solution")
+	for _, name := range slices.Sorted(maps.Keys(config.MetricWeights)) {
+		_, ok := Metrics[name]
+		check(ok, "unknown metric %q in metricWeights (available: %s)", name, strings.Join(MetricNames(), ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidGeneratorConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// LoadGeneratorConfig reads a config file in JSON, or TOML if it has a .toml extension,
+// with the same keys as the JSON. Settings not in the file are left as they are in config.
+func LoadGeneratorConfig(filePath string, config GeneratorConfig) (GeneratorConfig, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return config, fmt.Errorf("failed to read generator config %s: %w", filePath, err)
+	}
+	if strings.EqualFold(filepath.Ext(filePath), ".toml") {
+		data, err = simpleTOMLToJSON(data)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse generator config %s: %w", filePath, err)
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("failed to parse generator config %s: %w", filePath, err)
+	}
+	return config, nil
+}
+
+// simpleTOMLToJSON converts the subset of TOML needed for the generator config to a JSON object:
+// "key = value" lines with numbers or booleans, # comments, and tables of those for map settings,
+// written as a [metricWeights] section or with dotted keys like metricWeights.solutionLength = 1.
+func simpleTOMLToJSON(data []byte) ([]byte, error) {
+	var fields []jsonField
+	tables := map[string][]jsonField{}
+	var tableNames []string // in order of appearance
+	table := ""             // the current [table] section, or "" before any
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutSuffix(strings.TrimPrefix(line, "["), "]")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" || strings.ContainsAny(name, "[].\"") {
+				return nil, fmt.Errorf("line %d: expected a table name like [metricWeights]", lineNumber)
+			}
+			table = name
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		key = strings.TrimSpace(key)
+		keyTable := table
+		if before, after, dotted := strings.Cut(key, "."); dotted {
+			if table != "" || strings.Contains(after, ".") {
+				return nil, fmt.Errorf("line %d: only one level of tables is supported, but got %s", lineNumber, key)
+			}
+			keyTable, key = strings.TrimSpace(before), strings.TrimSpace(after)
+		}
+		// TOML allows underscores between digits, like 10_000
+		value = strings.ReplaceAll(strings.TrimSpace(value), "_", "")
+		var number json.Number
+		if err := json.Unmarshal([]byte(value), &number); err != nil && value != "true" && value != "false" {
+			return nil, fmt.Errorf("line %d: expected a number or boolean for %s, got %s", lineNumber, key, value)
+		}
+		field := jsonField{key: key, value: json.RawMessage(value)}
+		if keyTable == "" {
+			fields = append(fields, field)
+			continue
+		}
+		if _, ok := tables[keyTable]; !ok {
+			tableNames = append(tableNames, keyTable)
+		}
+		tables[keyTable] = append(tables[keyTable], field)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, name := range tableNames {
+		object, err := marshalJSONObjectFields(tables[name])
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{key: name, value: object})
+	}
+	return marshalJSONObjectFields(fields)
+}
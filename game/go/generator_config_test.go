@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDefaultGeneratorConfigIsValid(t *testing.T) {
+	if err := DefaultGeneratorConfig().Validate(); err != nil {
+		t.Errorf("Expected default config to be valid, but got %v", err)
+	}
+}
+
+func TestGeneratorConfigValidate(t *testing.T) {
+	for name, modify := range map[string]func(*GeneratorConfig){
+		"width range":           func(c *GeneratorConfig) { c.MinWidth, c.MaxWidth = 5, 4 },
+		"zero height":           func(c *GeneratorConfig) { c.MinHeight = 0 },
+		"no food":               func(c *GeneratorConfig) { c.FoodChance = 0 },
+		"density":               func(c *GeneratorConfig) { c.BlockDensity = 1.5 },
+		"snake range":           func(c *GeneratorConfig) { c.MinSnakes, c.MaxSnakes = 3, 1 },
+		"snakes too short":      func(c *GeneratorConfig) { c.MinSnakeLength, c.MaxSnakeLength = 1, 1 },
+		"snakes too long":       func(c *GeneratorConfig) { c.MaxWidth, c.MaxHeight, c.MinSnakeLength = 2, 2, 5 },
+		"no tries":              func(c *GeneratorConfig) { c.Tries = 0 },
+		"no steps":              func(c *GeneratorConfig) { c.Steps = 0 },
+		"snake length backward": func(c *GeneratorConfig) { c.MinSnakeLength, c.MaxSnakeLength = 6, 3 },
+		"weights without solve": func(c *GeneratorConfig) { c.MetricWeights = map[string]float64{"pinchPoints": 1} },
+		"unknown metric":        func(c *GeneratorConfig) { c.Solve, c.MetricWeights = true, map[string]float64{"fun": 1} },
+	} {
+		config := DefaultGeneratorConfig()
+		modify(&config)
+		if err := config.Validate(); !errors.Is(err, ErrInvalidGeneratorConfig) {
+			t.Errorf("%s: expected ErrInvalidGeneratorConfig, but got %v", name, err)
+		}
+		if _, err := GenerateLevelWithRand(rand.New(rand.NewSource(1)), config); !errors.Is(err, ErrInvalidGeneratorConfig) {
+			t.Errorf("%s: expected generation to fail up front, but got %v", name, err)
+		}
+	}
+}
+
+func TestLoadGeneratorConfig(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "config.json")
+	tomlPath := filepath.Join(dir, "config.toml")
+	os.WriteFile(jsonPath, []byte(`{"maxWidth": 4, "blockDensity": 0.5, "steps": 5000}`), 0644)
+	os.WriteFile(tomlPath, []byte("# Narrow levels\nmaxWidth = 4\nblockDensity = 0.5 # more walls\n\nsteps = 5_000\n"), 0644)
+
+	expected := DefaultGeneratorConfig()
+	expected.MaxWidth = 4
+	expected.BlockDensity = 0.5
+	expected.Steps = 5000
+	for _, path := range []string{jsonPath, tomlPath} {
+		config, err := LoadGeneratorConfig(path, DefaultGeneratorConfig())
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", filepath.Base(path), err)
+		}
+		if !reflect.DeepEqual(config, expected) {
+			t.Errorf("%s: expected %+v, but got %+v", filepath.Base(path), expected, config)
+		}
+	}
+
+	os.WriteFile(tomlPath, []byte("maxWdith = 4\n"), 0644)
+	if _, err := LoadGeneratorConfig(tomlPath, DefaultGeneratorConfig()); err == nil {
+		t.Errorf("Expected an error for an unknown key")
+	}
+	os.WriteFile(tomlPath, []byte("[generator]\nmaxWidth = 4\n"), 0644)
+	if _, err := LoadGeneratorConfig(tomlPath, DefaultGeneratorConfig()); err == nil {
+		t.Errorf("Expected an error for an unknown table")
+	}
+	os.WriteFile(tomlPath, []byte("metricWeights.solutionLength.x = 1\n"), 0644)
+	if _, err := LoadGeneratorConfig(tomlPath, DefaultGeneratorConfig()); err == nil {
+		t.Errorf("Expected an error for nested tables")
+	}
+}
+
+func TestLoadGeneratorConfigTOMLMetricWeights(t *testing.T) {
+	dir := t.TempDir()
+	tomlPath := filepath.Join(dir, "config.toml")
+	expected := map[string]float64{"solutionLength": 1, "snakeSwitches": -0.5}
+	for _, toml := range []string{
+		"solve = true\n\n[metricWeights]\nsolutionLength = 1\nsnakeSwitches = -0.5 # fewer switches\n",
+		"solve = true\nmetricWeights.solutionLength = 1\nmetricWeights.snakeSwitches = -0.5\n",
+	} {
+		os.WriteFile(tomlPath, []byte(toml), 0644)
+		config, err := LoadGeneratorConfig(tomlPath, DefaultGeneratorConfig())
+		if err != nil {
+			t.Fatalf("Failed to load %q: %v", toml, err)
+		}
+		if !config.Solve || !reflect.DeepEqual(config.MetricWeights, expected) {
+			t.Errorf("%q: expected solve and metric weights %v, but got %v and %v", toml, expected, config.Solve, config.MetricWeights)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Tags distinguishing entity types in the canonical encoding.
+// These must not change, since hashes may be stored.
+const (
+	canonicalTagFood byte = iota + 1
+	canonicalTagInverter
+	canonicalTagCrate
+	canonicalTagCellularAutomata
+	canonicalTagSnake
+)
+
+func appendCanonicalInt(b []byte, value int) []byte {
+	return binary.AppendVarint(b, int64(value))
+}
+
+func appendCanonicalString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// AppendCanonicalBytes appends an encoding of the level's state to b,
+// such that two levels have the same encoding if and only if they are Equal.
+// Entity order is included, since it affects which entities are on top, and Equal compares entities in order.
+// It only matters for overlapping entities, so states that differ only in the order of separate entities
+// are distinct, and may each be searched by the solvers. That extra search cost is accepted to keep the encoding exact.
+func AppendCanonicalBytes(b []byte, level *Level) []byte {
+	b = appendCanonicalInt(b, level.Info.Width)
+	b = appendCanonicalInt(b, level.Info.Height)
+	for _, row := range level.Grid {
+		for _, layer := range row {
+			b = append(b, byte(layer))
+		}
+	}
+	b = binary.AppendUvarint(b, uint64(len(level.Entities)))
+	for _, entity := range level.Entities {
+		b = appendCanonicalEntity(b, entity)
+	}
+	return b
+}
+
+func appendCanonicalEntity(b []byte, entity Entity) []byte {
+	switch e := entity.(type) {
+	case *Food:
+		b = append(b, canonicalTagFood, byte(e.Layer))
+		b = appendCanonicalInt(appendCanonicalInt(b, e.Position.X), e.Position.Y)
+	case *Inverter:
+		b = append(b, canonicalTagInverter, byte(e.Layer))
+		b = appendCanonicalInt(appendCanonicalInt(b, e.Position.X), e.Position.Y)
+	case *Crate:
+		b = append(b, canonicalTagCrate, byte(e.Layer))
+		b = appendCanonicalInt(appendCanonicalInt(b, e.Position.X), e.Position.Y)
+	case *CellularAutomata:
+		b = append(b, canonicalTagCellularAutomata, byte(e.Layer))
+		b = appendCanonicalInt(appendCanonicalInt(b, e.Position.X), e.Position.Y)
+	case *Snake:
+		b = append(b, canonicalTagSnake, byte(e.Layer))
+		b = appendCanonicalString(b, e.ID)
+		if e.GrowOnNextMove {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+		b = binary.AppendUvarint(b, uint64(len(e.FusedSnakeIDs)))
+		for _, id := range e.FusedSnakeIDs {
+			b = appendCanonicalString(b, id)
+		}
+		b = binary.AppendUvarint(b, uint64(len(e.Segments)))
+		for _, segment := range e.Segments {
+			b = appendCanonicalInt(appendCanonicalInt(b, segment.X), segment.Y)
+		}
+	default:
+		panic("Unknown entity type during canonical encoding")
+	}
+	return b
+}
+
+// CanonicalBytes returns an encoding of the level's state.
+// Converted to a string, it can be used as a map key with no risk of collisions.
+func CanonicalBytes(level *Level) []byte {
+	return AppendCanonicalBytes(nil, level)
+}
+
+// Hash returns a 64-bit FNV-1a hash of the level's canonical encoding.
+// It is stable across runs and platforms, so it can be stored,
+// but different levels may collide, so use CanonicalBytes when that matters.
+func Hash(level *Level) uint64 {
+	return hashCanonicalBytes(CanonicalBytes(level))
+}
+
+func hashCanonicalBytes(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
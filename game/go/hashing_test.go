@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestHashMatchesEquality(t *testing.T) {
+	level, err := LoadLevel("levels/easy/002-switching-snakes.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	copied := copyLevel(level)
+	if Hash(level) != Hash(copied) || string(CanonicalBytes(level)) != string(CanonicalBytes(copied)) {
+		t.Errorf("Expected copies to hash the same")
+	}
+
+	seen := map[uint64]*Level{Hash(level): level}
+	for _, move := range getAllPossibleMoves(copied) {
+		moved := copyLevel(level)
+		input := MoveToMoveInput(move)
+		TakeMove(AnalyzeMoveRelative(getSnakeByID(input.SnakeID, moved), input.Direction.X, input.Direction.Y, moved), moved)
+		hash := Hash(moved)
+		if other, ok := seen[hash]; ok {
+			t.Errorf("Hash collision between different states:\n%v\n%v", other, moved)
+		}
+		seen[hash] = moved
+	}
+
+	// Swapping entity order changes which is on top, so it should change the hash.
+	swapped := copyLevel(level)
+	swapped.Entities[0], swapped.Entities[1] = swapped.Entities[1], swapped.Entities[0]
+	if Hash(swapped) == Hash(level) {
+		t.Errorf("Expected entity order to affect the hash")
+	}
+
+	snake := getSnakes(copied)[0]
+	snake.GrowOnNextMove = !snake.GrowOnNextMove
+	if Hash(copied) == Hash(level) {
+		t.Errorf("Expected GrowOnNextMove to affect the hash")
+	}
+}
+
+func TestHashIsStable(t *testing.T) {
+	// Hashes may be stored, so they shouldn't change between versions unless necessary.
+	level := &Level{
+		Info:     LevelInfo{Width: 2, Height: 1},
+		Grid:     [][]CollisionLayer{{Black, White}},
+		Entities: []Entity{&Snake{ID: "a", Layer: White, Segments: []Point{{X: 0, Y: 0}}}, &Food{Position: Point{X: 1, Y: 0}, Layer: White}},
+	}
+	const expected = uint64(0xb66ae398b59d04a3)
+	if hash := Hash(level); hash != expected {
+		t.Errorf("Expected hash %#x, but got %#x", expected, hash)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"maps"
 	"math/rand"
 	"slices"
 )
@@ -85,6 +86,39 @@ func withinLevel(point Point, level *Level) bool {
 	return point.X >= 0 && point.X < level.Info.Width && point.Y >= 0 && point.Y < level.Info.Height
 }
 
+// Equivalent to lineNoDiagonals() in helpers.ts, but returns a slice instead of a generator.
+func lineNoDiagonals(start, end Point) []Point {
+	xDist := abs(end.X - start.X)
+	yDist := -abs(end.Y - start.Y)
+	xStep := -1
+	if start.X < end.X {
+		xStep = 1
+	}
+	yStep := -1
+	if start.Y < end.Y {
+		yStep = 1
+	}
+
+	x, y := start.X, start.Y
+	err := xDist + yDist
+	points := []Point{}
+	for {
+		points = append(points, Point{X: x, Y: y})
+		if x == end.X && y == end.Y {
+			return points
+		}
+		if 2*err-yDist > xDist-2*err {
+			// horizontal step
+			err += yDist
+			x += xStep
+		} else {
+			// vertical step
+			err += xDist
+			y += yStep
+		}
+	}
+}
+
 func indexOfEntity(entity Entity, level *Level) int {
 	for i := range level.Entities {
 		// if snake.ID == entity.ID {
@@ -119,6 +153,52 @@ func topLayer(hits []Hit) CollisionLayer {
 	return Both
 }
 
+// Returns the first hit on a solid entity or the grid, or nil if there is none.
+func firstSolidHit(hits []Hit) *Hit {
+	for i := range hits {
+		if hits[i].Entity == nil || hits[i].Entity.IsSolid() {
+			return &hits[i]
+		}
+	}
+	return nil
+}
+
+func translateEntity(entity Entity, deltaX, deltaY int) {
+	switch e := entity.(type) {
+	case *Food:
+		e.Position.X += deltaX
+		e.Position.Y += deltaY
+	case *Inverter:
+		e.Position.X += deltaX
+		e.Position.Y += deltaY
+	case *Crate:
+		e.Position.X += deltaX
+		e.Position.Y += deltaY
+	case *Snake:
+		for i := range e.Segments {
+			e.Segments[i].X += deltaX
+			e.Segments[i].Y += deltaY
+		}
+	}
+}
+
+// Ensure collectables are on top.
+// TODO: rule should be crates are below anything they are not inside of*,
+// which is complicated, as in this game, crates can be inside of crates inside of snakes inside of crates.
+// *except walls? or does that count as being inside of the wall?
+func sortEntities(level *Level) {
+	slices.SortStableFunc(level.Entities, func(a, b Entity) int {
+		_, aIsCollectable := a.(Collectable)
+		_, bIsCollectable := b.(Collectable)
+		if aIsCollectable == bIsCollectable {
+			return 0
+		} else if aIsCollectable {
+			return 1
+		}
+		return -1
+	})
+}
+
 // Called "hitTestAllEntities" in original TS code,
 // but now should be called "hitTestAllEntitiesAndGrid" since the blocks are no longer entities.
 func hitTestAllEntities(x, y int, level *Level, options HitTestOptions) []Hit {
@@ -158,12 +238,28 @@ func copyLevel(level *Level) *Level {
 				Position: Point{X: e.Position.X, Y: e.Position.Y},
 				Layer:    e.Layer,
 			}
+		case *Inverter:
+			newEntities[i] = &Inverter{
+				Position: Point{X: e.Position.X, Y: e.Position.Y},
+				Layer:    e.Layer,
+			}
+		case *CellularAutomata:
+			newEntities[i] = &CellularAutomata{
+				Position: Point{X: e.Position.X, Y: e.Position.Y},
+				Layer:    e.Layer,
+			}
+		case *Crate:
+			newEntities[i] = &Crate{
+				Position: Point{X: e.Position.X, Y: e.Position.Y},
+				Layer:    e.Layer,
+			}
 		case *Snake:
 			newEntities[i] = &Snake{
 				ID:             e.ID,
 				Segments:       slices.Clone(e.Segments),
 				GrowOnNextMove: e.GrowOnNextMove,
 				Layer:          e.Layer,
+				FusedSnakeIDs:  slices.Clone(e.FusedSnakeIDs),
 			}
 		default:
 			panic("Unknown entity type during level copy")
@@ -201,9 +297,24 @@ func Equal(level *Level, other *Level) bool {
 			if !ok || e.Position != o.Position || e.Layer != o.Layer {
 				return false
 			}
+		case *Inverter:
+			o, ok := other.Entities[i].(*Inverter)
+			if !ok || e.Position != o.Position || e.Layer != o.Layer {
+				return false
+			}
+		case *CellularAutomata:
+			o, ok := other.Entities[i].(*CellularAutomata)
+			if !ok || e.Position != o.Position || e.Layer != o.Layer {
+				return false
+			}
+		case *Crate:
+			o, ok := other.Entities[i].(*Crate)
+			if !ok || e.Position != o.Position || e.Layer != o.Layer {
+				return false
+			}
 		case *Snake:
 			o, ok := other.Entities[i].(*Snake)
-			if !ok || e.ID != o.ID || e.GrowOnNextMove != o.GrowOnNextMove || !slices.Equal(e.Segments, o.Segments) || e.Layer != o.Layer {
+			if !ok || e.ID != o.ID || e.GrowOnNextMove != o.GrowOnNextMove || !slices.Equal(e.Segments, o.Segments) || e.Layer != o.Layer || !slices.Equal(e.FusedSnakeIDs, o.FusedSnakeIDs) {
 				return false
 			}
 		default:
@@ -214,11 +325,54 @@ func Equal(level *Level, other *Level) bool {
 	return true
 }
 
+// equalIgnoringSeparateOrder is like Equal, but the order of entities only matters where they overlap,
+// since all it affects is which is on top. The TS version keeps snakes ordered among blocks,
+// which are grid cells here, so recordings from it can order entities that are apart differently.
+func equalIgnoringSeparateOrder(level *Level, other *Level) bool {
+	sorted := func(level *Level) *Level {
+		level = copyLevel(level)
+		slices.SortStableFunc(level.Entities, func(a, b Entity) int {
+			return slices.Compare(appendCanonicalEntity(nil, a), appendCanonicalEntity(nil, b))
+		})
+		return level
+	}
+	if !Equal(sorted(level), sorted(other)) {
+		return false
+	}
+	// With the same entities, the order is the same where they overlap if every tile has them in the same order.
+	stacks := func(level *Level) map[Point][]string {
+		stacks := map[Point][]string{}
+		for _, entity := range level.Entities {
+			key := string(appendCanonicalEntity(nil, entity))
+			switch e := entity.(type) {
+			case *Snake:
+				for _, segment := range e.Segments {
+					stacks[segment] = append(stacks[segment], key)
+				}
+			case *Food:
+				stacks[e.Position] = append(stacks[e.Position], key)
+			case *Inverter:
+				stacks[e.Position] = append(stacks[e.Position], key)
+			case *Crate:
+				stacks[e.Position] = append(stacks[e.Position], key)
+			case *CellularAutomata:
+				stacks[e.Position] = append(stacks[e.Position], key)
+			}
+		}
+		return stacks
+	}
+	return maps.EqualFunc(stacks(level), stacks(other), slices.Equal[[]string])
+}
+
 func copyGame(g *Game) *Game {
 	game := &Game{
 		level:     copyLevel(g.level),
 		levelId:   g.levelId,
 		levelName: g.levelName,
+		moves:     g.moves,
+		// The copy has the same level state, so the dead state check still applies.
+		deadStateChecked: g.deadStateChecked,
+		deadReasons:      g.deadReasons,
 	}
 	for _, entity := range game.level.Entities {
 		if snake, ok := entity.(*Snake); ok {
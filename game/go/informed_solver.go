@@ -0,0 +1,270 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+)
+
+// A Heuristic estimates the number of moves needed to win from a state.
+// To guarantee optimal solutions, it must never overestimate (admissible),
+// and must change by at most one per move (consistent).
+// It may return heuristicUnreachable if the level can't be won from the state.
+type Heuristic func(level *Level) int
+
+const heuristicUnreachable = math.MaxInt32
+
+// HeuristicFoodDistance is the largest distance from any remaining food to the nearest snake head able to eat it.
+// Every move moves a head by one tile, so it can bring each food at most one tile closer,
+// except when a fused snake is dragged towards food being pushed, which can close the gap by two.
+func HeuristicFoodDistance(level *Level) int {
+	hasInverters, hasFusedSnakes := false, false
+	for _, entity := range level.Entities {
+		switch e := entity.(type) {
+		case *Inverter:
+			hasInverters = true
+		case *Snake:
+			hasFusedSnakes = hasFusedSnakes || len(e.FusedSnakeIDs) > 0
+		}
+	}
+	snakes := getSnakes(level)
+	estimate := 0
+	for _, entity := range level.Entities {
+		food, ok := entity.(*Food)
+		if !ok {
+			continue
+		}
+		nearest := heuristicUnreachable
+		for _, snake := range snakes {
+			// Without inverters, snakes never change layer, so only snakes on a matching layer can eat the food.
+			if len(snake.Segments) == 0 || (!hasInverters && !layersCollide(food.Layer, snake.Layer)) {
+				continue
+			}
+			head := snake.Segments[0]
+			nearest = min(nearest, abs(food.Position.X-head.X)+abs(food.Position.Y-head.Y))
+		}
+		if nearest == heuristicUnreachable {
+			return heuristicUnreachable
+		}
+		estimate = max(estimate, nearest)
+	}
+	if hasFusedSnakes {
+		return (estimate + 1) / 2
+	}
+	return estimate
+}
+
+// HeuristicFoodCount is based on the number of tiles with food left.
+// A move eats the food on at most one tile, but may also push food onto another tile with food,
+// so it's halved if there's anything that can push food.
+func HeuristicFoodCount(level *Level) int {
+	tiles := map[Point]bool{}
+	canPush := false
+	for _, entity := range level.Entities {
+		switch e := entity.(type) {
+		case *Food:
+			tiles[e.Position] = true
+		case *Crate:
+			canPush = true
+		}
+	}
+	if canPush {
+		return (len(tiles) + 1) / 2
+	}
+	return len(tiles)
+}
+
+// HeuristicCombined is the maximum of the other heuristics, which is still admissible and consistent.
+func HeuristicCombined(level *Level) int {
+	return max(HeuristicFoodDistance(level), HeuristicFoodCount(level))
+}
+
+var Heuristics = map[string]Heuristic{
+	"distance": HeuristicFoodDistance,
+	"count":    HeuristicFoodCount,
+	"combined": HeuristicCombined,
+}
+
+type InformedSolveOptions struct {
+	SolveOptions
+	Heuristic Heuristic // defaults to HeuristicCombined
+}
+
+// SolveStats counts the work done by a search, for comparing heuristics.
+type SolveStats struct {
+	Expanded  int // states whose successors were generated
+	Generated int // successor states generated, including duplicates
+}
+
+type aStarItem struct {
+	level *Level
+	node  int // index into nodes
+	f     int
+}
+
+// aStarQueue is a priority queue ordered by f = g + h, preferring deeper states on ties,
+// since they're likely closer to the goal.
+type aStarQueue struct {
+	items []aStarItem
+	nodes *[]solverNode
+}
+
+func (q aStarQueue) Len() int { return len(q.items) }
+func (q aStarQueue) Less(i, j int) bool {
+	if q.items[i].f != q.items[j].f {
+		return q.items[i].f < q.items[j].f
+	}
+	return (*q.nodes)[q.items[i].node].depth > (*q.nodes)[q.items[j].node].depth
+}
+func (q aStarQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *aStarQueue) Push(x any)   { q.items = append(q.items, x.(aStarItem)) }
+func (q *aStarQueue) Pop() any {
+	item := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return item
+}
+
+// SolveAStar finds a shortest solution using A* search, guided by the heuristic.
+// It returns the same errors as Solve.
+func SolveAStar(level *Level, opts InformedSolveOptions) ([]MoveInput, SolveStats, error) {
+	heuristic := opts.Heuristic
+	if heuristic == nil {
+		heuristic = HeuristicCombined
+	}
+	var stats SolveStats
+	h := heuristic(level)
+	if h == heuristicUnreachable {
+		return nil, stats, fmt.Errorf("%w: heuristic shows the initial state can't be won", ErrUnsolvable)
+	}
+	if dead, reasons := IsDeadState(level); dead {
+		return nil, stats, deadStateError(reasons)
+	}
+
+	nodes := []solverNode{{parent: -1}}
+	queue := &aStarQueue{nodes: &nodes}
+	heap.Push(queue, aStarItem{level: copyLevel(level), node: 0, f: h})
+	// Best known number of moves to reach each state
+	bestDepths := map[string]int{string(CanonicalBytes(level)): 0}
+	limited := false
+
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(aStarItem)
+		depth := nodes[item.node].depth
+		if levelIsWon(item.level) {
+			return solutionTo(nodes, item.node), stats, nil
+		}
+		if bestDepths[string(CanonicalBytes(item.level))] < depth {
+			continue // a shorter path to this state was found after this one was queued
+		}
+		if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+			limited = true
+			continue
+		}
+
+		stats.Expanded++
+		for _, successor := range successors(item.level) {
+			stats.Generated++
+			key := string(CanonicalBytes(successor.level))
+			if best, ok := bestDepths[key]; ok && best <= depth+1 {
+				continue
+			}
+			h := heuristic(successor.level)
+			if h == heuristicUnreachable || isDeadState(successor.level) {
+				continue
+			}
+			if opts.MaxStates > 0 && len(bestDepths) >= opts.MaxStates {
+				return nil, stats, fmt.Errorf("%w: visited %d states without finding a solution", ErrSearchLimitReached, len(bestDepths))
+			}
+			bestDepths[key] = depth + 1
+			nodes = append(nodes, solverNode{parent: item.node, moveInput: successor.moveInput, depth: depth + 1})
+			heap.Push(queue, aStarItem{level: successor.level, node: len(nodes) - 1, f: depth + 1 + h})
+		}
+	}
+
+	if limited {
+		return nil, stats, fmt.Errorf("%w: no solution within %d moves (visited %d states)", ErrSearchLimitReached, opts.MaxDepth, len(bestDepths))
+	}
+	return nil, stats, fmt.Errorf("%w: exhausted all %d reachable states", ErrUnsolvable, len(bestDepths))
+}
+
+// SolveIDAStar finds a shortest solution using iterative deepening A*,
+// which repeats a depth-first search with an increasing bound on g + h.
+// It uses less memory than A* for the search frontier, but still remembers
+// the shallowest depth each state was seen at within an iteration, to avoid re-exploring it.
+// MaxStates limits the total number of states generated across all iterations.
+func SolveIDAStar(level *Level, opts InformedSolveOptions) ([]MoveInput, SolveStats, error) {
+	heuristic := opts.Heuristic
+	if heuristic == nil {
+		heuristic = HeuristicCombined
+	}
+	var stats SolveStats
+	bound := heuristic(level)
+	if bound == heuristicUnreachable {
+		return nil, stats, fmt.Errorf("%w: heuristic shows the initial state can't be won", ErrUnsolvable)
+	}
+	if dead, reasons := IsDeadState(level); dead {
+		return nil, stats, deadStateError(reasons)
+	}
+
+	path := []MoveInput{}
+	var seenDepths map[string]int
+	limitReached := false
+	// search returns true if a solution was found, otherwise the smallest f that exceeded the bound.
+	var search func(level *Level, depth int) (bool, int)
+	search = func(level *Level, depth int) (bool, int) {
+		h := heuristic(level)
+		if h == heuristicUnreachable || isDeadState(level) {
+			return false, heuristicUnreachable
+		}
+		f := depth + h
+		if f > bound {
+			return false, f
+		}
+		if levelIsWon(level) {
+			return true, f
+		}
+		stats.Expanded++
+		next := heuristicUnreachable
+		for _, successor := range successors(level) {
+			stats.Generated++
+			if opts.MaxStates > 0 && stats.Generated > opts.MaxStates {
+				limitReached = true
+				return false, heuristicUnreachable
+			}
+			key := string(CanonicalBytes(successor.level))
+			if seen, ok := seenDepths[key]; ok && seen <= depth+1 {
+				continue
+			}
+			seenDepths[key] = depth + 1
+			path = append(path, successor.moveInput)
+			found, exceeded := search(successor.level, depth+1)
+			if found {
+				return true, exceeded
+			}
+			path = path[:len(path)-1]
+			if limitReached {
+				return false, heuristicUnreachable
+			}
+			next = min(next, exceeded)
+		}
+		return false, next
+	}
+
+	for {
+		if opts.MaxDepth > 0 && bound > opts.MaxDepth {
+			return nil, stats, fmt.Errorf("%w: no solution within %d moves (generated %d states)", ErrSearchLimitReached, opts.MaxDepth, stats.Generated)
+		}
+		seenDepths = map[string]int{string(CanonicalBytes(level)): 0}
+		found, next := search(copyLevel(level), 0)
+		if found {
+			return path, stats, nil
+		}
+		if limitReached {
+			return nil, stats, fmt.Errorf("%w: generated %d states without finding a solution", ErrSearchLimitReached, stats.Generated)
+		}
+		if next == heuristicUnreachable {
+			return nil, stats, fmt.Errorf("%w: exhausted all reachable states", ErrUnsolvable)
+		}
+		bound = next
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInformedSolversFindShortestSolutions(t *testing.T) {
+	for _, levelId := range []string{
+		"levels/easy/001-movement.json",
+		"levels/tests/get-stuck-by-moving-down.json",
+		"levels/sketches/inverter.json",
+	} {
+		level, err := LoadLevel(levelId)
+		if err != nil {
+			t.Fatalf("Failed to load level %s: %v", levelId, err)
+		}
+		expected, err := Solve(level, SolveOptions{})
+		if err != nil {
+			t.Fatalf("Failed to solve level %s: %v", levelId, err)
+		}
+		for name, heuristic := range Heuristics {
+			aStarSolution, aStarStats, err := SolveAStar(level, InformedSolveOptions{Heuristic: heuristic})
+			if err != nil {
+				t.Fatalf("%s: A* with %s heuristic failed: %v", levelId, name, err)
+			}
+			if len(aStarSolution) != len(expected) {
+				t.Errorf("%s: A* with %s heuristic found %d moves, expected %d", levelId, name, len(aStarSolution), len(expected))
+			}
+			if aStarStats.Expanded == 0 || aStarStats.Generated < aStarStats.Expanded {
+				t.Errorf("%s: unexpected A* stats %+v", levelId, aStarStats)
+			}
+			idaStarSolution, _, err := SolveIDAStar(level, InformedSolveOptions{Heuristic: heuristic})
+			if err != nil {
+				t.Fatalf("%s: IDA* with %s heuristic failed: %v", levelId, name, err)
+			}
+			if len(idaStarSolution) != len(expected) {
+				t.Errorf("%s: IDA* with %s heuristic found %d moves, expected %d", levelId, name, len(idaStarSolution), len(expected))
+			}
+		}
+	}
+}
+
+func TestHeuristicsAreAdmissible(t *testing.T) {
+	level, err := LoadLevel("levels/tests/generated-level.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	solution, err := Solve(level, SolveOptions{})
+	if err != nil {
+		t.Fatalf("Failed to solve level: %v", err)
+	}
+	// Along a shortest solution, the remaining moves are the true distance to the goal.
+	for i, input := range solution {
+		for name, heuristic := range Heuristics {
+			if h := heuristic(level); h > len(solution)-i {
+				t.Errorf("%s heuristic overestimates after %d moves: %d > %d", name, i, h, len(solution)-i)
+			}
+		}
+		TakeMove(AnalyzeMoveRelative(getSnakeByID(input.SnakeID, level), input.Direction.X, input.Direction.Y, level), level)
+	}
+}
+
+func TestInformedSolversDetectWrongLayerFood(t *testing.T) {
+	// A white snake can't eat black food, and there's no inverter to change that.
+	level := &Level{
+		Info:     LevelInfo{Width: 3, Height: 1},
+		Grid:     [][]CollisionLayer{{Black, Black, Black}},
+		Entities: []Entity{&Snake{ID: "a", Layer: White, Segments: []Point{{X: 0, Y: 0}}}, &Food{Position: Point{X: 2, Y: 0}, Layer: Black}},
+	}
+	_, stats, err := SolveAStar(level, InformedSolveOptions{})
+	if !errors.Is(err, ErrUnsolvable) {
+		t.Errorf("Expected ErrUnsolvable from A*, but got %v", err)
+	}
+	if stats.Expanded != 0 {
+		t.Errorf("Expected A* not to expand any states, but got %+v", stats)
+	}
+	_, _, err = SolveIDAStar(level, InformedSolveOptions{})
+	if !errors.Is(err, ErrUnsolvable) {
+		t.Errorf("Expected ErrUnsolvable from IDA*, but got %v", err)
+	}
+}
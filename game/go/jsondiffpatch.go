@@ -0,0 +1,284 @@
+// Minimal port of the parts of jsondiffpatch used for playthroughs.
+// Operates on generic JSON values, as decoded by encoding/json into interface{}.
+// See https://github.com/benjamine/jsondiffpatch/blob/master/docs/deltas.md
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	deltaTypeDeleted   = 0
+	deltaTypeTextDiff  = 2
+	deltaTypeArrayMove = 3
+)
+
+// jsonInt accepts numbers as decoded from JSON (float64) or as constructed in Go (int).
+func jsonInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	}
+	return 0, false
+}
+
+func deepCopyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		newMap := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			newMap[key] = deepCopyJSON(item)
+		}
+		return newMap
+	case []interface{}:
+		newSlice := make([]interface{}, len(v))
+		for i, item := range v {
+			newSlice[i] = deepCopyJSON(item)
+		}
+		return newSlice
+	default:
+		return v
+	}
+}
+
+// Equivalent to the objectHash function passed to jsondiffpatch.create in game-state.ts,
+// except that objects without an ID are only matched if they're identical.
+func jsonItemsMatch(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	aMap, aIsMap := a.(map[string]interface{})
+	bMap, bIsMap := b.(map[string]interface{})
+	if !aIsMap || !bIsMap {
+		return false
+	}
+	aID, aHasID := aMap["id"].(string)
+	bID, bHasID := bMap["id"].(string)
+	return aHasID && bHasID && aID == bID
+}
+
+// diffJSON returns a jsondiffpatch delta, or nil if the values are equal.
+func diffJSON(a, b interface{}) interface{} {
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+	aMap, aIsMap := a.(map[string]interface{})
+	bMap, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		delta := map[string]interface{}{}
+		for key, aValue := range aMap {
+			if bValue, ok := bMap[key]; ok {
+				if d := diffJSON(aValue, bValue); d != nil {
+					delta[key] = d
+				}
+			} else {
+				delta[key] = []interface{}{aValue, 0, deltaTypeDeleted}
+			}
+		}
+		for key, bValue := range bMap {
+			if _, ok := aMap[key]; !ok {
+				delta[key] = []interface{}{bValue}
+			}
+		}
+		return delta
+	}
+	aSlice, aIsSlice := a.([]interface{})
+	bSlice, bIsSlice := b.([]interface{})
+	if aIsSlice && bIsSlice {
+		return diffJSONArrays(aSlice, bSlice)
+	}
+	return []interface{}{a, b}
+}
+
+func diffJSONArrays(a, b []interface{}) interface{} {
+	delta := map[string]interface{}{"_t": "a"}
+
+	// Trim common head and tail, then match up the rest with a longest common subsequence.
+	head := 0
+	for head < len(a) && head < len(b) && jsonItemsMatch(a[head], b[head]) {
+		if d := diffJSON(a[head], b[head]); d != nil {
+			delta[strconv.Itoa(head)] = d
+		}
+		head++
+	}
+	tail := 0
+	for tail < len(a)-head && tail < len(b)-head && jsonItemsMatch(a[len(a)-1-tail], b[len(b)-1-tail]) {
+		if d := diffJSON(a[len(a)-1-tail], b[len(b)-1-tail]); d != nil {
+			delta[strconv.Itoa(len(b)-1-tail)] = d
+		}
+		tail++
+	}
+	middleA := a[head : len(a)-tail]
+	middleB := b[head : len(b)-tail]
+
+	lengths := make([][]int, len(middleA)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(middleB)+1)
+	}
+	for i := len(middleA) - 1; i >= 0; i-- {
+		for j := len(middleB) - 1; j >= 0; j-- {
+			if jsonItemsMatch(middleA[i], middleB[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(middleA) || j < len(middleB) {
+		switch {
+		case i < len(middleA) && j < len(middleB) && jsonItemsMatch(middleA[i], middleB[j]):
+			if d := diffJSON(middleA[i], middleB[j]); d != nil {
+				delta[strconv.Itoa(head+j)] = d
+			}
+			i++
+			j++
+		case j >= len(middleB) || (i < len(middleA) && lengths[i+1][j] >= lengths[i][j+1]):
+			delta["_"+strconv.Itoa(head+i)] = []interface{}{middleA[i], 0, deltaTypeDeleted}
+			i++
+		default:
+			delta[strconv.Itoa(head+j)] = []interface{}{middleB[j]}
+			j++
+		}
+	}
+
+	if len(delta) == 1 {
+		return nil
+	}
+	return delta
+}
+
+// patchJSON applies a jsondiffpatch delta to a value, modifying it in place where possible.
+// The returned bool is true if the value was deleted.
+func patchJSON(value interface{}, delta interface{}) (interface{}, bool, error) {
+	if delta == nil {
+		return value, false, nil
+	}
+	if deltaSlice, ok := delta.([]interface{}); ok {
+		switch len(deltaSlice) {
+		case 1:
+			return deltaSlice[0], false, nil
+		case 2:
+			return deltaSlice[1], false, nil
+		case 3:
+			if deltaType, _ := jsonInt(deltaSlice[2]); deltaType == deltaTypeDeleted {
+				return nil, true, nil
+			} else if deltaType == deltaTypeTextDiff {
+				return nil, false, fmt.Errorf("text diffs are not supported")
+			}
+		}
+		return nil, false, fmt.Errorf("invalid delta: %v", deltaSlice)
+	}
+	deltaMap, ok := delta.(map[string]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("invalid delta: %v", delta)
+	}
+	if deltaMap["_t"] == "a" {
+		valueSlice, ok := value.([]interface{})
+		if !ok {
+			return nil, false, fmt.Errorf("array delta applied to non-array value %v", value)
+		}
+		patched, err := patchJSONArray(valueSlice, deltaMap)
+		return patched, false, err
+	}
+	valueMap, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("object delta applied to non-object value %v", value)
+	}
+	for key, childDelta := range deltaMap {
+		patched, deleted, err := patchJSON(valueMap[key], childDelta)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", key, err)
+		}
+		if deleted {
+			delete(valueMap, key)
+		} else {
+			valueMap[key] = patched
+		}
+	}
+	return valueMap, false, nil
+}
+
+type jsonArrayInsertion struct {
+	index int
+	value interface{}
+}
+
+func patchJSONArray(array []interface{}, delta map[string]interface{}) ([]interface{}, error) {
+	var toRemove []int
+	var toInsert []jsonArrayInsertion
+	toModify := map[int]interface{}{}
+	for key, childDelta := range delta {
+		if key == "_t" {
+			continue
+		}
+		if strings.HasPrefix(key, "_") {
+			index, err := strconv.Atoi(key[1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid array delta key %q", key)
+			}
+			toRemove = append(toRemove, index)
+			continue
+		}
+		index, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid array delta key %q", key)
+		}
+		if childSlice, ok := childDelta.([]interface{}); ok && len(childSlice) == 1 {
+			toInsert = append(toInsert, jsonArrayInsertion{index: index, value: childSlice[0]})
+		} else {
+			toModify[index] = childDelta
+		}
+	}
+
+	// Remove items, from the end so that indices remain valid
+	sort.Sort(sort.Reverse(sort.IntSlice(toRemove)))
+	for _, index := range toRemove {
+		if index < 0 || index >= len(array) {
+			return nil, fmt.Errorf("array delta removes index %d out of range", index)
+		}
+		removed := array[index]
+		array = append(array[:index], array[index+1:]...)
+		removal, _ := delta["_"+strconv.Itoa(index)].([]interface{})
+		if len(removal) != 3 {
+			return nil, fmt.Errorf("invalid array removal delta at index %d", index)
+		}
+		if deltaType, _ := jsonInt(removal[2]); deltaType == deltaTypeArrayMove {
+			to, _ := jsonInt(removal[1])
+			toInsert = append(toInsert, jsonArrayInsertion{index: to, value: removed})
+		}
+	}
+
+	// Insert items, from the start, since indices refer to the final array
+	sort.SliceStable(toInsert, func(i, j int) bool { return toInsert[i].index < toInsert[j].index })
+	for _, insertion := range toInsert {
+		if insertion.index < 0 {
+			return nil, fmt.Errorf("array delta inserts at index %d out of range", insertion.index)
+		}
+		// Like Array.prototype.splice, inserting past the end appends.
+		index := min(insertion.index, len(array))
+		array = append(array[:index], append([]interface{}{insertion.value}, array[index:]...)...)
+	}
+
+	for index, childDelta := range toModify {
+		if index < 0 || index >= len(array) {
+			return nil, fmt.Errorf("array delta modifies index %d out of range", index)
+		}
+		patched, deleted, err := patchJSON(array[index], childDelta)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", index, err)
+		}
+		if deleted {
+			return nil, fmt.Errorf("array delta deletes index %d without removing it", index)
+		}
+		array[index] = patched
+	}
+	return array, nil
+}
@@ -4,38 +4,102 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"reflect"
 	"slices"
 )
 
+type GeneratedLevel struct {
+	Level *Level
+	// Solution is a shortest solution, proving the level can be won, if the config has Solve set.
+	Solution []MoveInput
+	Score    float64
+	// Metrics are the values of all available metrics, if the config has Solve set.
+	Metrics map[string]float64
+}
+
+// GenerateLevel generates a level with the default config from a random seed, which is recorded in the level info.
 func GenerateLevel() (*Level, error) {
-	const tries = 200
-	var bestComplexity int
-	var bestLevel *Level
+	generated, err := GenerateLevelFromSeed(rand.Int63(), DefaultGeneratorConfig())
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Best complexity found: %g\n", generated.Score)
+	return generated.Level, nil
+}
+
+// GenerateLevelFromSeed generates a level reproducibly, recording the seed in the level info,
+// along with the config if it's not the default.
+func GenerateLevelFromSeed(seed int64, config GeneratorConfig) (GeneratedLevel, error) {
+	generated, err := GenerateLevelWithRand(rand.New(rand.NewSource(seed)), config)
+	if err != nil {
+		return generated, fmt.Errorf("seed %d: %w", seed, err)
+	}
+	generated.Level.Info.Generator = &GeneratorInfo{Seed: seed}
+	if !reflect.DeepEqual(config, DefaultGeneratorConfig()) {
+		generated.Level.Info.Generator.Config = &config
+	}
+	return generated, nil
+}
+
+// GenerateLevelWithRand generates a level using only the given source of randomness,
+// so the same sequence of random numbers and config always gives the same level.
+func GenerateLevelWithRand(rng *rand.Rand, config GeneratorConfig) (GeneratedLevel, error) {
+	if err := config.Validate(); err != nil {
+		return GeneratedLevel{}, err
+	}
+	tries := config.Tries
+	var best GeneratedLevel
 	for i := 0; i < tries; i++ {
-		level, complexity := tryGenerateLevel()
+		level, complexity := tryGenerateLevel(rng, config)
 		// time.Sleep(100 * time.Millisecond)
-		if level != nil && complexity > bestComplexity {
-			bestComplexity = complexity
-			bestLevel = level
+		if level == nil {
+			continue
+		}
+		candidate := GeneratedLevel{Level: level, Score: float64(complexity)}
+		if config.Solve {
+			// The solver doesn't use the random numbers, so whether or not it's used,
+			// the same candidates are generated.
+			var ok bool
+			candidate, ok = verifyGeneratedLevel(level, config)
+			if !ok {
+				continue
+			}
+		}
+		if best.Level == nil || candidate.Score > best.Score {
+			best = candidate
 		}
 	}
-	fmt.Fprintf(os.Stderr, "Best complexity found: %d\n", bestComplexity)
-	if bestLevel == nil {
-		return nil, fmt.Errorf("failed to generate any valid level in %d tries", tries)
+	if best.Level == nil {
+		return best, fmt.Errorf("failed to generate any valid level in %d tries", tries)
+	}
+	if config.Solve {
+		best.Metrics = MeasureAll(best.Level, best.Solution)
+	}
+	return best, nil
+}
+
+// verifyGeneratedLevel solves a candidate level, scoring it by the config's metric weights,
+// or else the length of a shortest solution, which is also stored as the par.
+// It returns false if the level is unsolvable, or too hard to solve within the limits, or trivial.
+func verifyGeneratedLevel(level *Level, config GeneratorConfig) (GeneratedLevel, bool) {
+	solution, err := Solve(level, SolveOptions{MaxStates: config.SolverMaxStates})
+	if err != nil || len(solution) < config.MinSolutionLength {
+		return GeneratedLevel{}, false
+	}
+	level.Info.Par = len(solution)
+	score := float64(len(solution))
+	if len(config.MetricWeights) > 0 {
+		// Metric names are checked by config.Validate()
+		score, _ = WeightedScore(level, solution, config.MetricWeights)
 	}
-	return bestLevel, nil
+	return GeneratedLevel{Level: level, Solution: solution, Score: score}, true
 }
 
-func tryGenerateLevel() (*Level, int) {
-	const puzzleGenerationLimit = 10000
+func tryGenerateLevel(rng *rand.Rand, config GeneratorConfig) (*Level, int) {
 	const targetPuzzleComplexity = 1000
-	const blockDensity = 0.3
-	const foodChance = 0.9
 
-	// I think smaller levels should be statistically more likely to generate
-	// "puzzle"-like levels rather than meaningless traversal.
-	width := rand.Intn(5) + 2
-	height := rand.Intn(5) + 2
+	width := rng.Intn(config.MaxWidth-config.MinWidth+1) + config.MinWidth
+	height := rng.Intn(config.MaxHeight-config.MinHeight+1) + config.MinHeight
 
 	level := &Level{
 		Info:     LevelInfo{Width: width, Height: height},
@@ -47,7 +111,7 @@ func tryGenerateLevel() (*Level, int) {
 	for i := range level.Grid {
 		level.Grid[i] = make([]CollisionLayer, width)
 		for j := range level.Grid[i] {
-			if rand.Float32() < blockDensity {
+			if rng.Float32() < float32(config.BlockDensity) {
 				level.Grid[i][j] = White
 			} else {
 				level.Grid[i][j] = Black
@@ -56,10 +120,10 @@ func tryGenerateLevel() (*Level, int) {
 	}
 
 	// Create snakes
-	numSnakes := rand.Intn(3) + 1
+	numSnakes := rng.Intn(config.MaxSnakes-config.MinSnakes+1) + config.MinSnakes
 	for i := 0; i < numSnakes; i++ {
-		x := rand.Intn(width)
-		y := rand.Intn(height)
+		x := rng.Intn(width)
+		y := rng.Intn(height)
 		// Get layer before appending snake so we don't retrieve the snake's own (uninitialized) layer
 		layer := invertCollisionLayer(topLayerAt(x, y, level))
 		// append early (before topLayerAt) so that hit tests include the snake itself
@@ -67,10 +131,10 @@ func tryGenerateLevel() (*Level, int) {
 		snake := level.Entities[i].(*Snake)
 		snake.Segments = []Point{{X: x, Y: y}}
 		snake.Layer = layer
-		targetSnakeEndLength := 2 + rand.Intn(10)
+		targetSnakeEndLength := config.MinSnakeLength + rng.Intn(config.MaxSnakeLength-config.MinSnakeLength+1)
 		for j := 1; j < targetSnakeEndLength; j++ {
 			// Try to place the next segment in a random direction
-			directionOrder := rand.Perm(len(CardinalDirections))
+			directionOrder := rng.Perm(len(CardinalDirections))
 			for _, directionIndex := range directionOrder {
 				direction := CardinalDirections[directionIndex]
 				if !withinLevel(Point{X: x + direction.X, Y: y + direction.Y}, level) {
@@ -88,10 +152,10 @@ func tryGenerateLevel() (*Level, int) {
 
 	// Simulate in reverse, occasionally creating collectables and shrinking snakes as they move backwards
 	var moves []Move
-	for i := 0; i < puzzleGenerationLimit; i++ {
+	for i := 0; i < config.Steps; i++ {
 		snakes := getSnakes(level)
-		snake := snakes[rand.Intn(len(snakes))]
-		direction := CardinalDirections[rand.Intn(len(CardinalDirections))]
+		snake := snakes[rng.Intn(len(snakes))]
+		direction := CardinalDirections[rng.Intn(len(CardinalDirections))]
 		potentialBeforeTile := Point{
 			X: snake.Segments[len(snake.Segments)-1].X - direction.X,
 			Y: snake.Segments[len(snake.Segments)-1].Y - direction.Y,
@@ -108,7 +172,7 @@ func tryGenerateLevel() (*Level, int) {
 			prevGrowOnNextMove := snake.GrowOnNextMove
 			// const eat = Math.random() < foodChance && snake.segments.length > 1
 			previousHead := snake.Segments[0]
-			eat := rand.Float32() < foodChance && len(snake.Segments) > 1 &&
+			eat := rng.Float32() < float32(config.FoodChance) && len(snake.Segments) > 1 &&
 				// prevent generating food on top of other food
 				!slices.ContainsFunc(hitTestAllEntities(previousHead.X, previousHead.Y, level, HitTestOptions{}), func(hit Hit) bool {
 					_, isFood := hit.Entity.(*Food)
@@ -119,6 +183,7 @@ func tryGenerateLevel() (*Level, int) {
 			snake.GrowOnNextMove = eat
 			expected := copyLevel(level)
 			// FIXME: it's not validating in the case that it generates a collectable
+			// (With GeneratorConfig.Solve, the whole level is verified by the solver afterwards.)
 			if eat {
 				food := &Food{}
 				food.Position = previousHead
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGenerateLevelFromSeedIsReproducible(t *testing.T) {
+	var outputs [][]byte
+	for i := 0; i < 2; i++ {
+		generated, err := GenerateLevelFromSeed(42, DefaultGeneratorConfig())
+		if err != nil {
+			t.Fatalf("Failed to generate level: %v", err)
+		}
+		serialized, err := SerializeLevel(generated.Level)
+		if err != nil {
+			t.Fatalf("Failed to serialize level: %v", err)
+		}
+		outputs = append(outputs, serialized)
+	}
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Errorf("Expected the same seed to give identical output")
+	}
+
+	level, err := DeserializeLevel(outputs[0])
+	if err != nil {
+		t.Fatalf("Failed to deserialize level: %v", err)
+	}
+	if level.Info.Generator == nil || level.Info.Generator.Seed != 42 {
+		t.Errorf("Expected the seed to be recorded in the level info, but got %+v", level.Info.Generator)
+	} else if level.Info.Generator.Config != nil {
+		t.Errorf("Expected the default config not to be recorded")
+	}
+}
+
+func TestGenerateSolvedLevel(t *testing.T) {
+	config := DefaultGeneratorConfig()
+	config.Tries = 20
+	config.Solve = true
+	generated, err := GenerateLevelFromSeed(1, config)
+	if err != nil {
+		t.Fatalf("Failed to generate level: %v", err)
+	}
+	level := generated.Level
+	if len(generated.Solution) < config.MinSolutionLength || generated.Score != float64(len(generated.Solution)) || level.Info.Par != len(generated.Solution) {
+		t.Errorf("Expected a non-trivial solution as the score and par, but got %d moves, score %g, par %d", len(generated.Solution), generated.Score, level.Info.Par)
+	}
+	if level.Info.Generator == nil || level.Info.Generator.Config == nil || !level.Info.Generator.Config.Solve {
+		t.Errorf("Expected the config to be recorded in the level info")
+	}
+
+	// The solution should be a valid proof, including when saved as a playthrough.
+	playthrough, err := SerializePlaythrough(level, generated.Solution)
+	if err != nil {
+		t.Fatalf("Failed to serialize playthrough: %v", err)
+	}
+	states, moveInputs, err := DeserializePlaythrough(playthrough)
+	if err != nil {
+		t.Fatalf("Failed to deserialize playthrough: %v", err)
+	}
+	if len(moveInputs) != len(generated.Solution) || !levelIsWon(states[len(states)-1]) {
+		t.Errorf("Expected the playthrough to win in %d moves", len(generated.Solution))
+	}
+}
+
+func TestGenerateLevelRankedByMetrics(t *testing.T) {
+	config := DefaultGeneratorConfig()
+	config.Tries = 20
+	config.Solve = true
+	config.MetricWeights = map[string]float64{"snakeSwitches": 1, "pinchPoints": 0.5}
+	generated, err := GenerateLevelFromSeed(1, config)
+	if err != nil {
+		t.Fatalf("Failed to generate level: %v", err)
+	}
+	expected := generated.Metrics["snakeSwitches"] + generated.Metrics["pinchPoints"]*0.5
+	if generated.Score != expected {
+		t.Errorf("Expected the score to be the weighted sum of metrics %v, but got %g", generated.Metrics, generated.Score)
+	}
+}
+
+func TestGenerateLevelWithNonPositiveScores(t *testing.T) {
+	for _, weights := range []map[string]float64{
+		{"snakeSwitches": 0},
+		{"solutionLength": -1}, // preferring shorter solutions
+	} {
+		config := DefaultGeneratorConfig()
+		config.Tries = 20
+		config.Solve = true
+		config.MetricWeights = weights
+		generated, err := GenerateLevelFromSeed(1, config)
+		if err != nil {
+			t.Errorf("%v: failed to generate level: %v", weights, err)
+		} else if generated.Score > 0 {
+			t.Errorf("%v: expected a score of at most 0, but got %g", weights, generated.Score)
+		}
+	}
+}
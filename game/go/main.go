@@ -4,7 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/urfave/cli/v3"
 )
@@ -35,6 +40,528 @@ func main() {
 				Usage: "use ASCII rendering instead of Unicode, for better compatibility with some terminals",
 			},
 		},
+		Commands: []*cli.Command{
+			{
+				Name:  "generate",
+				Usage: "generate a random level",
+				Description: "Settings are taken from the defaults, then the --config file, then the other flags.\n" +
+					"The config file is JSON, or TOML if it has a .toml extension, with keys like \"maxWidth\",\n" +
+					"and metric weights in a [metricWeights] table.",
+				Flags: append([]cli.Flag{
+					&cli.Int64Flag{
+						Name:  "seed",
+						Usage: "seed for reproducible generation (default: random)",
+					},
+					&cli.StringFlag{
+						Name:  "config",
+						Usage: "generator config file (JSON or TOML)",
+					},
+					&cli.StringFlag{
+						Name:  "playthrough",
+						Usage: "file to write the solution to, as a playthrough (requires --solve)",
+					},
+					&cli.IntFlag{
+						Name:  "count",
+						Value: 1,
+						Usage: "number of distinct levels to generate into the --out directory, from consecutive seeds",
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "directory to write levels and a manifest.json to, instead of printing a level",
+					},
+					&cli.IntFlag{
+						Name:  "workers",
+						Value: 0,
+						Usage: "with --out, number of levels to generate in parallel (0 for the number of CPUs)",
+					},
+					&cli.IntFlag{
+						Name:  "max-seeds",
+						Value: 0,
+						Usage: "with --out, give up after trying this many seeds (0 for ten times --count)",
+					},
+				}, generatorConfigFlags()...),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					config, err := generatorConfigFromFlags(cmd)
+					if err != nil {
+						return err
+					}
+					seed := rand.Int63()
+					if cmd.IsSet("seed") {
+						seed = cmd.Int64("seed")
+					}
+					fmt.Fprintf(os.Stderr, "Seed: %d\n", seed)
+					if outDir := cmd.String("out"); outDir != "" {
+						if cmd.IsSet("playthrough") {
+							return fmt.Errorf("--playthrough can't be used with --out; solutions are written to the --out directory")
+						}
+						ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+						defer stop()
+						manifest, err := GenerateBatch(ctx, BatchOptions{
+							Config:   config,
+							Count:    int(cmd.Int("count")),
+							OutDir:   outDir,
+							Seed:     seed,
+							MaxSeeds: int(cmd.Int("max-seeds")),
+							Workers:  int(cmd.Int("workers")),
+							Progress: func(entry BatchManifestEntry) {
+								fmt.Fprintf(os.Stderr, "Wrote %s (score %g)\n", filepath.Join(outDir, entry.File), entry.Score)
+							},
+						})
+						fmt.Fprintf(os.Stderr, "Wrote %d levels from %d seeds, skipping %d duplicates and %d failures\n",
+							len(manifest.Levels), manifest.SeedsTried, manifest.Duplicates, manifest.Failures)
+						return err
+					}
+					if cmd.Int("count") != 1 {
+						return fmt.Errorf("--count requires --out")
+					}
+					if cmd.IsSet("playthrough") && !config.Solve {
+						return fmt.Errorf("--playthrough requires --solve")
+					}
+					generated, err := GenerateLevelFromSeed(seed, config)
+					if err != nil {
+						return fmt.Errorf("failed to generate level: %w", err)
+					}
+					fmt.Fprintf(os.Stderr, "Best complexity found: %g\n", generated.Score)
+					serialized, err := SerializeLevel(generated.Level)
+					if err != nil {
+						return fmt.Errorf("failed to serialize level: %w", err)
+					}
+					fmt.Println(string(serialized))
+					if config.Solve {
+						fmt.Fprintf(os.Stderr, "Solution in %d moves: %s\n", len(generated.Solution), String(generated.Solution))
+					}
+					if playthroughPath := cmd.String("playthrough"); playthroughPath != "" {
+						playthrough, err := SerializePlaythrough(generated.Level, generated.Solution)
+						if err != nil {
+							return fmt.Errorf("failed to serialize playthrough: %w", err)
+						}
+						return os.WriteFile(playthroughPath, playthrough, 0644)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "solve",
+				Usage:     "find a shortest solution to a level",
+				ArgsUsage: "<level>",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "max-depth",
+						Value: 0,
+						Usage: "give up on solutions longer than this many moves (0 for no limit)",
+					},
+					&cli.IntFlag{
+						Name:  "max-states",
+						Value: 0,
+						Usage: "give up after visiting this many states (0 for no limit)",
+					},
+					&cli.StringFlag{
+						Name:  "algorithm",
+						Value: "bfs",
+						Usage: "search algorithm: bfs, astar or idastar",
+					},
+					&cli.StringFlag{
+						Name:  "heuristic",
+						Value: "combined",
+						Usage: "heuristic for astar and idastar: distance, count or combined",
+					},
+					&cli.BoolFlag{
+						Name:  "parallel",
+						Value: false,
+						Usage: "search using multiple CPU cores, reporting progress",
+					},
+					&cli.IntFlag{
+						Name:  "workers",
+						Value: 0,
+						Usage: "number of goroutines for --parallel (0 for one per CPU)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					level, err := loadLevelArg(cmd)
+					if err != nil {
+						return err
+					}
+					solveOptions := SolveOptions{
+						MaxDepth:  int(cmd.Int("max-depth")),
+						MaxStates: int(cmd.Int("max-states")),
+					}
+					var solution []MoveInput
+					algorithm := cmd.String("algorithm")
+					if algorithm == "astar" || algorithm == "idastar" {
+						heuristic, ok := Heuristics[cmd.String("heuristic")]
+						if !ok {
+							return fmt.Errorf("unknown heuristic %q", cmd.String("heuristic"))
+						}
+						informedOptions := InformedSolveOptions{SolveOptions: solveOptions, Heuristic: heuristic}
+						var stats SolveStats
+						if algorithm == "astar" {
+							solution, stats, err = SolveAStar(level, informedOptions)
+						} else {
+							solution, stats, err = SolveIDAStar(level, informedOptions)
+						}
+						fmt.Fprintf(os.Stderr, "Expanded %d states, generated %d states\n", stats.Expanded, stats.Generated)
+					} else if algorithm != "bfs" {
+						return fmt.Errorf("unknown algorithm %q", algorithm)
+					} else if cmd.Bool("parallel") {
+						ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+						defer stop()
+						solution, err = SolveParallel(ctx, level, ParallelSolveOptions{
+							SolveOptions: solveOptions,
+							Workers:      int(cmd.Int("workers")),
+							Progress: func(progress SolveProgress) {
+								fmt.Fprintf(os.Stderr, "Depth %d, frontier %d, visited %d states\n", progress.Depth, progress.Frontier, progress.Visited)
+							},
+						})
+					} else {
+						solution, err = Solve(level, solveOptions)
+					}
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Solved in %d moves:\n%s\n", len(solution), String(solution))
+					return nil
+				},
+			},
+			{
+				Name:      "count-solutions",
+				Usage:     "count the distinct solutions to a level, to check whether it has a unique solution",
+				ArgsUsage: "<level>",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "max-count",
+						Value: 1000,
+						Usage: "stop counting at this many solutions (0 for no limit)",
+					},
+					&cli.IntFlag{
+						Name:  "max-depth",
+						Value: 0,
+						Usage: "don't consider solutions longer than this many moves (0 for no limit)",
+					},
+					&cli.IntFlag{
+						Name:  "max-states",
+						Value: 0,
+						Usage: "give up after visiting this many states (0 for no limit)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					level, err := loadLevelArg(cmd)
+					if err != nil {
+						return err
+					}
+					count, err := CountSolutions(level, CountSolutionsOptions{
+						SolveOptions: SolveOptions{
+							MaxDepth:  int(cmd.Int("max-depth")),
+							MaxStates: int(cmd.Int("max-states")),
+						},
+						MaxCount: int(cmd.Int("max-count")),
+					})
+					if err != nil {
+						return err
+					}
+					atLeast := ""
+					if !count.Complete {
+						atLeast = "at least "
+					}
+					fmt.Printf("Shortest solution: %d moves\n%s\n", count.ShortestLength, String(count.Example))
+					fmt.Printf("Distinct shortest solutions: %s%d\n", atLeast, count.Shortest)
+					fmt.Printf("Distinct winning end states: %s%d\n", atLeast, count.WinningStates)
+					switch {
+					case count.Unique():
+						fmt.Println("The level has a unique solution.")
+					case count.Complete || count.Shortest > 1 || count.WinningStates > 1:
+						fmt.Println("The level does not have a unique solution.")
+					default:
+						fmt.Println("Could not determine whether the level has a unique solution, due to search limits.")
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "par",
+				Usage:     "compute the optimal number of moves to win a level",
+				ArgsUsage: "<level>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "write",
+						Value: false,
+						Usage: "store the par in the level file",
+					},
+					&cli.IntFlag{
+						Name:  "max-depth",
+						Value: 0,
+						Usage: "give up on solutions longer than this many moves (0 for no limit)",
+					},
+					&cli.IntFlag{
+						Name:  "max-states",
+						Value: 0,
+						Usage: "give up after visiting this many states (0 for no limit)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					levelId, err := levelIdArg(cmd)
+					if err != nil {
+						return err
+					}
+					level, err := LoadLevel(levelId)
+					if err != nil {
+						return err
+					}
+					par, err := ComputePar(level, SolveOptions{
+						MaxDepth:  int(cmd.Int("max-depth")),
+						MaxStates: int(cmd.Int("max-states")),
+					})
+					if err != nil {
+						return err
+					}
+					if level.Info.Par != 0 && level.Info.Par != par {
+						fmt.Fprintf(os.Stderr, "Stored par was %d\n", level.Info.Par)
+					}
+					fmt.Printf("Par: %d moves\n", par)
+					if cmd.Bool("write") {
+						return WriteLevelPar(levelId, par)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "simplify",
+				Usage:     "shorten a playthrough of a level",
+				ArgsUsage: "<level> <playthrough>",
+				Description: "Removes loops and detours from a playthrough, such as levels/hard/yin-yang-full-playthrough.json,\n" +
+					"writing a playthrough that can be replayed in the web version of the game.",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "out",
+						Value: "",
+						Usage: "file to write the simplified playthrough to (default: standard output)",
+					},
+					&cli.IntFlag{
+						Name:  "max-depth",
+						Value: maxSubsequenceLength,
+						Usage: "longest sequence of moves to search for as a shortcut",
+					},
+					&cli.IntFlag{
+						Name:  "max-states",
+						Value: 100000,
+						Usage: "give up searching for a shortcut from a state after visiting this many states (0 for no limit)",
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Value: 0,
+						Usage: "stop simplifying after this long, keeping what was simplified so far (0 for no limit)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					levelId, err := levelIdArg(cmd)
+					if err != nil {
+						return err
+					}
+					playthroughId := cmd.Args().Get(1)
+					if playthroughId == "" {
+						return fmt.Errorf("expected a playthrough, such as levels/easy/001-movement-playthrough.json")
+					}
+					ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+					defer stop()
+					serialized, report, err := SimplifyPlaythroughFile(ctx, levelId, playthroughId, SimplifyOptions{
+						MaxDepth:    int(cmd.Int("max-depth")),
+						MaxStates:   int(cmd.Int("max-states")),
+						MaxDuration: cmd.Duration("timeout"),
+					})
+					if err != nil {
+						if serialized == nil {
+							return err
+						}
+						fmt.Fprintf(os.Stderr, "Interrupted, keeping what was simplified so far.\n")
+					} else if !report.Complete {
+						fmt.Fprintf(os.Stderr, "Ran out of time, keeping what was simplified so far.\n")
+					}
+					fmt.Fprintf(os.Stderr, "Moves: %d before, %d after\n", report.OriginalMoves, len(report.MoveInputs))
+					fmt.Fprintf(os.Stderr, "Removed %d moves from loops, and %d moves with %d shortcuts; %d snake switches left\n",
+						report.CycleMovesRemoved, report.ShortcutMovesRemoved, report.Shortcuts, report.SnakeSwitches)
+					if out := cmd.String("out"); out != "" {
+						return os.WriteFile(out, serialized, 0644)
+					}
+					fmt.Println(string(serialized))
+					return nil
+				},
+			},
+			{
+				Name:      "analyze",
+				Usage:     "measure puzzle quality metrics of a level",
+				ArgsUsage: "<level>",
+				Description: "Metrics are measured on a shortest solution found by the solver, or the given playthrough.\n" +
+					"Metrics: " + strings.Join(MetricNames(), ", "),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "playthrough",
+						Usage: "measure this playthrough instead of solving the level",
+					},
+					&cli.IntFlag{
+						Name:  "max-states",
+						Value: 0,
+						Usage: "give up solving after visiting this many states (0 for no limit)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					level, err := loadLevelArg(cmd)
+					if err != nil {
+						return err
+					}
+					var solution []MoveInput
+					if playthroughId := cmd.String("playthrough"); playthroughId != "" {
+						_, solution, err = LoadPlaythrough(playthroughId)
+					} else {
+						solution, err = Solve(level, SolveOptions{MaxStates: int(cmd.Int("max-states"))})
+					}
+					if err != nil {
+						return err
+					}
+					values := MeasureAll(level, solution)
+					for _, name := range MetricNames() {
+						fmt.Printf("%-16s %g\n", name, values[name])
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "evolve",
+				Usage:     "improve a level by mutating it, keeping mutants that are solvable and score higher",
+				ArgsUsage: "<level>",
+				Description: "Mutations toggle grid cells, move food, lengthen or shorten snakes, and shift snakes.\n" +
+					"Mutants are scored with --metric-weight, like --metric-weight snakeSwitches=1, or by solution length by default.\n" +
+					"Metrics: " + strings.Join(MetricNames(), ", "),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "playthrough",
+						Usage: "a solution to the level, needed if it's too hard to solve within --solver-max-states",
+					},
+					&cli.Int64Flag{
+						Name:  "seed",
+						Usage: "seed for reproducible mutations (default: random)",
+					},
+					&cli.IntFlag{
+						Name:  "iterations",
+						Value: 200,
+						Usage: "number of mutants to try",
+					},
+					&cli.IntFlag{
+						Name:  "mutations",
+						Value: 1,
+						Usage: "number of mutations to apply to make each mutant",
+					},
+					&cli.IntFlag{
+						Name:  "solver-max-states",
+						Value: 20000,
+						Usage: "give up solving a mutant after visiting this many states, and try the previous solution on it instead (0 for no limit)",
+					},
+					&cli.StringMapFlag{
+						Name:  "metric-weight",
+						Usage: "score levels by a weighted sum of metrics, like pinchPoints=1",
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "file to write the evolved level to (default: standard output)",
+					},
+					&cli.StringFlag{
+						Name:  "out-playthrough",
+						Usage: "file to write the evolved level's solution to, as a playthrough",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					level, err := loadLevelArg(cmd)
+					if err != nil {
+						return err
+					}
+					var solution []MoveInput
+					if playthroughId := cmd.String("playthrough"); playthroughId != "" {
+						_, solution, err = LoadPlaythrough(playthroughId)
+						if err != nil {
+							return err
+						}
+					}
+					weights, err := metricWeightsFromFlags(cmd)
+					if err != nil {
+						return err
+					}
+					seed := rand.Int63()
+					if cmd.IsSet("seed") {
+						seed = cmd.Int64("seed")
+					}
+					fmt.Fprintf(os.Stderr, "Seed: %d\n", seed)
+					ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+					defer stop()
+					result, err := EvolveLevel(ctx, rand.New(rand.NewSource(seed)), level, solution, EvolveOptions{
+						Weights:         weights,
+						Iterations:      int(cmd.Int("iterations")),
+						Mutations:       int(cmd.Int("mutations")),
+						SolverMaxStates: int(cmd.Int("solver-max-states")),
+						Progress: func(step EvolveStep) {
+							fmt.Fprintf(os.Stderr, "Iteration %d: score %g after %s\n", step.Iteration, step.Score, strings.Join(step.Mutations, ", "))
+						},
+					})
+					if err != nil {
+						if result.Metrics == nil {
+							return err
+						}
+						fmt.Fprintf(os.Stderr, "Interrupted, keeping what was evolved so far.\n")
+					}
+					fmt.Fprintf(os.Stderr, "Kept %d of %d mutants, rejecting %d as invalid or unsolvable\n", len(result.Steps), result.Tried, result.Rejected)
+					if !result.Optimal {
+						fmt.Fprintf(os.Stderr, "The solution may not be a shortest one, so par is not set.\n")
+					}
+					serialized, err := SerializeLevel(result.Level)
+					if err != nil {
+						return fmt.Errorf("failed to serialize level: %w", err)
+					}
+					if out := cmd.String("out"); out != "" {
+						if err := os.WriteFile(out, serialized, 0644); err != nil {
+							return err
+						}
+					} else {
+						fmt.Println(string(serialized))
+					}
+					if out := cmd.String("out-playthrough"); out != "" {
+						playthrough, err := SerializePlaythrough(result.Level, result.Solution)
+						if err != nil {
+							return fmt.Errorf("failed to serialize playthrough: %w", err)
+						}
+						return os.WriteFile(out, playthrough, 0644)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "verify",
+				Usage:     "replay playthroughs to check they still win",
+				ArgsUsage: "[playthrough...]",
+				Description: "Replays the given playthroughs, or all playthroughs of campaign levels, through the engine.\n" +
+					"Exits with an error if any playthrough that's expected to win no longer does.",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					playthroughIds := cmd.Args().Slice()
+					if len(playthroughIds) == 0 {
+						var err error
+						playthroughIds, err = campaignPlaythroughs()
+						if err != nil {
+							return fmt.Errorf("failed to find playthroughs: %w", err)
+						}
+					}
+					regressions := 0
+					for _, playthroughId := range playthroughIds {
+						report := VerifyPlaythrough(playthroughId)
+						status := "ok"
+						if report.Regression() {
+							status = "REGRESSION"
+							regressions++
+						}
+						fmt.Printf("%-10s %s\n", status, report)
+					}
+					if regressions > 0 {
+						return fmt.Errorf("%d of %d playthroughs regressed", regressions, len(playthroughIds))
+					}
+					return nil
+				},
+			},
+		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			if cmd.Bool("generate") {
 				level, err := GenerateLevel()
@@ -69,3 +596,117 @@ func main() {
 	}
 
 }
+
+// loadLevelArg loads the level given as the first argument, by title or level ID.
+func loadLevelArg(cmd *cli.Command) (*Level, error) {
+	levelId, err := levelIdArg(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return LoadLevel(levelId)
+}
+
+// levelIdArg resolves the level given as the first argument, by title or level ID, to a level ID.
+func levelIdArg(cmd *cli.Command) (string, error) {
+	levelId := cmd.Args().First()
+	if levelId == "" {
+		return "", fmt.Errorf("expected a level title or ID, such as levels/easy/001-movement.json")
+	}
+	levels, err := getLevels()
+	if err != nil {
+		return "", fmt.Errorf("failed to list levels: %w", err)
+	}
+	for _, entry := range levels {
+		if entry.Title == levelId {
+			return entry.LevelId, nil
+		}
+	}
+	return levelId, nil
+}
+
+// generatorConfigFlags returns a flag for each GeneratorConfig setting, with the default values.
+func generatorConfigFlags() []cli.Flag {
+	defaults := DefaultGeneratorConfig()
+	return []cli.Flag{
+		&cli.IntFlag{Name: "min-width", Value: defaults.MinWidth, Usage: "minimum level width"},
+		&cli.IntFlag{Name: "max-width", Value: defaults.MaxWidth, Usage: "maximum level width"},
+		&cli.IntFlag{Name: "min-height", Value: defaults.MinHeight, Usage: "minimum level height"},
+		&cli.IntFlag{Name: "max-height", Value: defaults.MaxHeight, Usage: "maximum level height"},
+		&cli.FloatFlag{Name: "block-density", Value: defaults.BlockDensity, Usage: "chance of each tile being white"},
+		&cli.FloatFlag{Name: "food-chance", Value: defaults.FoodChance, Usage: "chance of leaving food behind when a snake moves backwards"},
+		&cli.IntFlag{Name: "min-snakes", Value: defaults.MinSnakes, Usage: "minimum number of snakes"},
+		&cli.IntFlag{Name: "max-snakes", Value: defaults.MaxSnakes, Usage: "maximum number of snakes"},
+		&cli.IntFlag{Name: "min-snake-length", Value: defaults.MinSnakeLength, Usage: "minimum snake length, before simulating in reverse"},
+		&cli.IntFlag{Name: "max-snake-length", Value: defaults.MaxSnakeLength, Usage: "maximum snake length, before simulating in reverse"},
+		&cli.IntFlag{Name: "tries", Value: defaults.Tries, Usage: "number of candidate levels to generate, keeping the best one"},
+		&cli.IntFlag{Name: "steps", Value: defaults.Steps, Usage: "number of reverse moves to attempt for each candidate"},
+		&cli.BoolFlag{Name: "solve", Value: defaults.Solve, Usage: "solve each candidate, dropping unsolvable and trivial levels, and scoring by solution length"},
+		&cli.IntFlag{Name: "min-solution-length", Value: defaults.MinSolutionLength, Usage: "with --solve, drop levels with shorter solutions"},
+		&cli.IntFlag{Name: "solver-max-states", Value: defaults.SolverMaxStates, Usage: "with --solve, drop levels that take more states to solve (0 for no limit)"},
+		&cli.StringMapFlag{Name: "metric-weight", Usage: "with --solve, score levels by a weighted sum of metrics, like pinchPoints=1 (metrics: " + strings.Join(MetricNames(), ", ") + ")"},
+	}
+}
+
+// generatorConfigFromFlags builds a GeneratorConfig from the defaults, the --config file, and flags that were set.
+func generatorConfigFromFlags(cmd *cli.Command) (GeneratorConfig, error) {
+	config := DefaultGeneratorConfig()
+	if configPath := cmd.String("config"); configPath != "" {
+		var err error
+		config, err = LoadGeneratorConfig(configPath, config)
+		if err != nil {
+			return config, err
+		}
+	}
+	ints := map[string]*int{
+		"min-width":           &config.MinWidth,
+		"max-width":           &config.MaxWidth,
+		"min-height":          &config.MinHeight,
+		"max-height":          &config.MaxHeight,
+		"min-snakes":          &config.MinSnakes,
+		"max-snakes":          &config.MaxSnakes,
+		"min-snake-length":    &config.MinSnakeLength,
+		"max-snake-length":    &config.MaxSnakeLength,
+		"tries":               &config.Tries,
+		"steps":               &config.Steps,
+		"min-solution-length": &config.MinSolutionLength,
+		"solver-max-states":   &config.SolverMaxStates,
+	}
+	for name, setting := range ints {
+		if cmd.IsSet(name) {
+			*setting = int(cmd.Int(name))
+		}
+	}
+	floats := map[string]*float64{
+		"block-density": &config.BlockDensity,
+		"food-chance":   &config.FoodChance,
+	}
+	for name, setting := range floats {
+		if cmd.IsSet(name) {
+			*setting = cmd.Float(name)
+		}
+	}
+	if cmd.IsSet("solve") {
+		config.Solve = cmd.Bool("solve")
+	}
+	if cmd.IsSet("metric-weight") {
+		weights, err := metricWeightsFromFlags(cmd)
+		if err != nil {
+			return config, err
+		}
+		config.MetricWeights = weights
+	}
+	return config, config.Validate()
+}
+
+// metricWeightsFromFlags parses --metric-weight name=weight flags.
+func metricWeightsFromFlags(cmd *cli.Command) (map[string]float64, error) {
+	weights := map[string]float64{}
+	for name, value := range cmd.StringMap("metric-weight") {
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight for metric %q: %w", name, err)
+		}
+		weights[name] = weight
+	}
+	return weights, nil
+}
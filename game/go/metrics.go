@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// A Metric measures some aspect of a level's puzzle quality, given a solution to it.
+// Higher values are meant to indicate more interesting puzzles, but metrics are best used in combination,
+// since each can be maximized by levels that aren't interesting in other ways.
+type Metric interface {
+	Measure(level *Level, solution []MoveInput) float64
+}
+
+// MetricFunc adapts a function to the Metric interface.
+type MetricFunc func(level *Level, solution []MoveInput) float64
+
+func (f MetricFunc) Measure(level *Level, solution []MoveInput) float64 {
+	return f(level, solution)
+}
+
+// Metrics are the metrics available to the analyze command and GeneratorConfig.MetricWeights, by name.
+// More can be added here.
+var Metrics = map[string]Metric{
+	"solutionLength":  MetricFunc(MetricSolutionLength),
+	"pinchPoints":     MetricFunc(MetricPinchPoints),
+	"snakeSwitches":   MetricFunc(MetricSnakeSwitches),
+	"snakesAsTerrain": MetricFunc(MetricSnakesAsTerrain),
+}
+
+// MetricNames returns the names of the available metrics, sorted.
+func MetricNames() []string {
+	return slices.Sorted(maps.Keys(Metrics))
+}
+
+// MeasureAll measures a level with every available metric.
+func MeasureAll(level *Level, solution []MoveInput) map[string]float64 {
+	values := make(map[string]float64, len(Metrics))
+	for name, metric := range Metrics {
+		values[name] = metric.Measure(level, solution)
+	}
+	return values
+}
+
+// WeightedScore combines metrics, weighted by name.
+// Metrics are summed in order of name, so the score doesn't vary in the last bits between runs.
+func WeightedScore(level *Level, solution []MoveInput, weights map[string]float64) (float64, error) {
+	score := 0.0
+	for _, name := range slices.Sorted(maps.Keys(weights)) {
+		metric, ok := Metrics[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown metric %q", name)
+		}
+		score += weights[name] * metric.Measure(level, solution)
+	}
+	return score, nil
+}
+
+func MetricSolutionLength(level *Level, solution []MoveInput) float64 {
+	return float64(len(solution))
+}
+
+// A state with this few possible moves, across all snakes, is a pinch point.
+const pinchPointMaxMoves = 2
+
+// MetricPinchPoints counts the states along the solution where the possible moves are limited.
+// This incentivizes tighter, less open-ended levels, although in the extreme it favors a corridor.
+func MetricPinchPoints(level *Level, solution []MoveInput) float64 {
+	pinchPoints := 0
+	for _, step := range traceSolution(level, solution) {
+		if step.possibleMoves <= pinchPointMaxMoves {
+			pinchPoints++
+		}
+	}
+	return float64(pinchPoints)
+}
+
+// MetricSnakeSwitches counts the times the solution switches snakes,
+// after reordering moves to switch as little as possible,
+// so that a disorganized solution doesn't inflate the count.
+func MetricSnakeSwitches(level *Level, solution []MoveInput) float64 {
+	return float64(countSnakeSwitches(groupSnakeMoves(solution, level)))
+}
+
+// MetricSnakesAsTerrain counts the times a snake moves onto another snake which is used later to eat food.
+// Without checking that the snake is used later, this would favor levels that meaninglessly use snakes as terrain.
+// Using snakes as terrain is core to the game, but only as a means to an end;
+// we want sequences where you have to use snakes together, not just visual noise.
+// Only moving onto a snake from off of it counts, not moving along it.
+func MetricSnakesAsTerrain(level *Level, solution []MoveInput) float64 {
+	steps := traceSolution(level, solution)
+	count := 0
+	for i, step := range steps {
+		for _, terrainID := range step.newlyOnSnakeIDs {
+			if slices.ContainsFunc(steps[i+1:], func(later solutionStep) bool {
+				return later.moveInput.SnakeID == terrainID && later.ateFood
+			}) {
+				count++
+			}
+		}
+	}
+	return float64(count)
+}
+
+// solutionStep describes a move of a solution, for metrics.
+type solutionStep struct {
+	moveInput     MoveInput
+	possibleMoves int // number of valid moves in the state before this move
+	// newlyOnSnakeIDs are the other snakes the moving snake's head moved onto, from a tile not on them.
+	newlyOnSnakeIDs []string
+	ateFood         bool
+}
+
+// traceSolution replays a solution, stopping at the first invalid move.
+func traceSolution(level *Level, solution []MoveInput) []solutionStep {
+	level = copyLevel(level)
+	steps := make([]solutionStep, 0, len(solution))
+	for _, input := range solution {
+		step := solutionStep{moveInput: input, possibleMoves: len(getAllPossibleMoves(level))}
+		move, ok := analyzeMoveInput(input, level)
+		if !ok {
+			break
+		}
+		previousHead := move.Snake.Segments[0]
+		for _, entity := range move.EntitiesThere {
+			if other, ok := entity.(*Snake); ok && other != move.Snake && !slices.Contains(other.Segments, previousHead) {
+				step.newlyOnSnakeIDs = append(step.newlyOnSnakeIDs, other.ID)
+			}
+		}
+		foodBefore := countFood(level)
+		TakeMove(move, level)
+		step.ateFood = countFood(level) < foodBefore
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+func countFood(level *Level) int {
+	count := 0
+	for _, entity := range level.Entities {
+		if _, ok := entity.(*Food); ok {
+			count++
+		}
+	}
+	return count
+}
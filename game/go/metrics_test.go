@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		levelId       string
+		playthroughId string
+		expected      map[string]float64
+	}{
+		{
+			levelId:       "levels/easy/001-movement.json",
+			playthroughId: "levels/easy/001-movement-playthrough.json",
+			expected:      map[string]float64{"solutionLength": 22, "snakeSwitches": 0, "snakesAsTerrain": 0, "pinchPoints": 14},
+		},
+		{
+			levelId:       "levels/easy/004-ferry.json",
+			playthroughId: "levels/easy/004-ferry-playthrough.json",
+			expected:      map[string]float64{"solutionLength": 39, "snakeSwitches": 4, "snakesAsTerrain": 1, "pinchPoints": 4},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.levelId, func(t *testing.T) {
+			level, err := LoadLevel(test.levelId)
+			if err != nil {
+				t.Fatalf("Failed to load level: %v", err)
+			}
+			_, moveInputs, err := LoadPlaythrough(test.playthroughId)
+			if err != nil {
+				t.Fatalf("Failed to load playthrough: %v", err)
+			}
+			values := MeasureAll(level, moveInputs)
+			for name, expected := range test.expected {
+				if values[name] != expected {
+					t.Errorf("Expected %s to be %g, but got %g", name, expected, values[name])
+				}
+			}
+		})
+	}
+}
+
+func TestWeightedScore(t *testing.T) {
+	level, err := LoadLevel("levels/easy/004-ferry.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	_, moveInputs, err := LoadPlaythrough("levels/easy/004-ferry-playthrough.json")
+	if err != nil {
+		t.Fatalf("Failed to load playthrough: %v", err)
+	}
+	score, err := WeightedScore(level, moveInputs, map[string]float64{"snakeSwitches": 2, "solutionLength": 0.5})
+	if err != nil {
+		t.Fatalf("Failed to score level: %v", err)
+	}
+	if score != 4*2+39*0.5 {
+		t.Errorf("Expected a weighted sum of metrics, but got %g", score)
+	}
+	if _, err := WeightedScore(level, moveInputs, map[string]float64{"fun": 1}); err == nil {
+		t.Errorf("Expected an error for an unknown metric")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+)
+
+// ComputePar finds the optimal number of moves to win the level, using the solver.
+func ComputePar(level *Level, opts SolveOptions) (int, error) {
+	solution, err := Solve(level, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute par: %w", err)
+	}
+	return len(solution), nil
+}
+
+// WriteLevelPar stores the par in a level file, relative to the public folder like LoadLevel.
+// The rest of the file is left as it was, rather than re-serializing the level,
+// which would reorder entities and drop anything the Go version doesn't know about.
+func WriteLevelPar(levelId string, par int) error {
+	filePath := path.Join("..", "public", levelId)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read level file %s: %w", levelId, err)
+	}
+	data, err = setLevelPar(data, par)
+	if err != nil {
+		return fmt.Errorf("failed to update level file %s: %w", levelId, err)
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+// setLevelPar sets levelInfo.par in level file JSON, preserving the order of keys,
+// and formatting it like JSON.stringify(data, null, 2) as the level editor does.
+func setLevelPar(data []byte, par int) ([]byte, error) {
+	fields, err := jsonObjectFields(data)
+	if err != nil {
+		return nil, err
+	}
+	levelInfo := json.RawMessage(`{}`)
+	for _, field := range fields {
+		if field.key == "levelInfo" {
+			levelInfo = field.value
+		}
+	}
+	levelInfoFields, err := jsonObjectFields(levelInfo)
+	if err != nil {
+		return nil, fmt.Errorf("levelInfo: %w", err)
+	}
+	parJSON, err := json.Marshal(par)
+	if err != nil {
+		return nil, err
+	}
+	levelInfo, err = marshalJSONObjectFields(setJSONObjectField(levelInfoFields, "par", parJSON))
+	if err != nil {
+		return nil, err
+	}
+	compact, err := marshalJSONObjectFields(setJSONObjectField(fields, "levelInfo", levelInfo))
+	if err != nil {
+		return nil, err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, compact, "", "  "); err != nil {
+		return nil, err
+	}
+	indented.WriteByte('\n')
+	return indented.Bytes(), nil
+}
+
+type jsonField struct {
+	key   string
+	value json.RawMessage
+}
+
+// jsonObjectFields parses a JSON object into its fields, in order.
+func jsonObjectFields(data []byte) ([]jsonField, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("%w: expected an object", ErrInvalidFormat)
+	}
+	var fields []jsonField
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var field jsonField
+		field.key = token.(string) // object keys are always strings
+		if err := decoder.Decode(&field.value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// setJSONObjectField replaces the value of a field, or adds it at the end.
+func setJSONObjectField(fields []jsonField, key string, value json.RawMessage) []jsonField {
+	for i, field := range fields {
+		if field.key == key {
+			fields[i].value = value
+			return fields
+		}
+	}
+	return append(fields, jsonField{key: key, value: value})
+}
+
+func marshalJSONObjectFields(fields []jsonField) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(field.value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComputePar(t *testing.T) {
+	level, err := LoadLevel("levels/easy/001-movement.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	par, err := ComputePar(level, SolveOptions{})
+	if err != nil {
+		t.Fatalf("Failed to compute par: %v", err)
+	}
+	if par != 22 {
+		t.Errorf("Expected par to be 22, but got %d", par)
+	}
+}
+
+func TestParRoundTrip(t *testing.T) {
+	level, err := LoadLevel("levels/easy/001-movement.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	serialized, err := SerializeLevel(level)
+	if err != nil {
+		t.Fatalf("Failed to serialize level: %v", err)
+	}
+	if strings.Contains(string(serialized), `"par"`) {
+		t.Errorf("Expected par to be omitted when unknown")
+	}
+
+	level.Info.Par = 22
+	serialized, err = SerializeLevel(level)
+	if err != nil {
+		t.Fatalf("Failed to serialize level: %v", err)
+	}
+	deserialized, err := DeserializeLevel(serialized)
+	if err != nil {
+		t.Fatalf("Failed to deserialize level: %v", err)
+	}
+	if deserialized.Info.Par != 22 {
+		t.Errorf("Expected par to round-trip, but got %d", deserialized.Info.Par)
+	}
+	if !Equal(level, deserialized) || Hash(level) != Hash(deserialized) {
+		t.Errorf("Expected level to be equal after round-trip")
+	}
+	deserialized.Info.Par = 0
+	if !Equal(level, deserialized) || Hash(level) != Hash(deserialized) {
+		t.Errorf("Expected par not to affect equality or hashing")
+	}
+}
+
+func TestSetLevelParPreservesFile(t *testing.T) {
+	original := `{
+  "format": "snakeshift",
+  "formatVersion": 6,
+  "levelInfo": {
+    "width": 2,
+    "height": 1
+  },
+  "entities": [
+    {
+      "y": 0,
+      "x": 1,
+      "width": 1,
+      "height": 1,
+      "layer": 1
+    }
+  ],
+  "entityTypes": [
+    "Food"
+  ],
+  "activePlayerEntityIndex": -1,
+  "levelId": ""
+}
+`
+	expected := strings.Replace(original, `"height": 1
+  },`, `"height": 1,
+    "par": 3
+  },`, 1)
+	updated, err := setLevelPar([]byte(original), 3)
+	if err != nil {
+		t.Fatalf("Failed to set par: %v", err)
+	}
+	if string(updated) != expected {
+		t.Errorf("Expected only par to be added, but got:\n%s", updated)
+	}
+	updated, err = setLevelPar(updated, 5)
+	if err != nil {
+		t.Fatalf("Failed to set par: %v", err)
+	}
+	if string(updated) != strings.Replace(expected, `"par": 3`, `"par": 5`, 1) {
+		t.Errorf("Expected par to be replaced, but got:\n%s", updated)
+	}
+}
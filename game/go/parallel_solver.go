@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type SolveProgress struct {
+	Visited  int // distinct states seen so far
+	Frontier int // states at the current depth
+	Depth    int // number of moves to reach the current frontier
+}
+
+type ParallelSolveOptions struct {
+	SolveOptions
+	Workers          int                 // number of goroutines, or 0 for runtime.NumCPU()
+	Progress         func(SolveProgress) // called periodically from a separate goroutine, if not nil
+	ProgressInterval time.Duration       // defaults to one second
+}
+
+const visitedSetShards = 64
+
+// visitedSet is a set of canonical state encodings, safe for concurrent use.
+// It's split into shards by hash to reduce lock contention.
+type visitedSet struct {
+	shards [visitedSetShards]struct {
+		sync.Mutex
+		states map[string]struct{}
+	}
+	size atomic.Int64
+}
+
+func newVisitedSet() *visitedSet {
+	v := &visitedSet{}
+	for i := range v.shards {
+		v.shards[i].states = map[string]struct{}{}
+	}
+	return v
+}
+
+// add returns true if the state wasn't already in the set.
+// If the set already has maxSize states (and maxSize > 0), a new state isn't added, and full is true.
+func (v *visitedSet) add(key []byte, maxSize int) (added, full bool) {
+	shard := &v.shards[hashCanonicalBytes(key)%visitedSetShards]
+	shard.Lock()
+	defer shard.Unlock()
+	if _, ok := shard.states[string(key)]; ok {
+		return false, false
+	}
+	// Other shards may be adding states at the same time, so the size is reserved before adding.
+	for {
+		size := v.size.Load()
+		if maxSize > 0 && size >= int64(maxSize) {
+			return false, true
+		}
+		if v.size.CompareAndSwap(size, size+1) {
+			break
+		}
+	}
+	shard.states[string(key)] = struct{}{}
+	return true, false
+}
+
+// parallelNode records how a state was reached. Unlike solverNode,
+// nodes are linked by pointer, since workers can't share an append-only slice.
+type parallelNode struct {
+	parent    *parallelNode
+	moveInput MoveInput
+}
+
+func (node *parallelNode) solution(depth int) []MoveInput {
+	moveInputs := make([]MoveInput, depth)
+	for ; node.parent != nil; node = node.parent {
+		depth--
+		moveInputs[depth] = node.moveInput
+	}
+	return moveInputs
+}
+
+type frontierEntry struct {
+	level *Level
+	node  *parallelNode
+}
+
+// SolveParallel is like Solve, but expands each depth of the search across multiple goroutines.
+// It searches depth by depth, so the solution is still a shortest one,
+// although which of several equally short solutions it returns may vary.
+func SolveParallel(ctx context.Context, level *Level, opts ParallelSolveOptions) ([]MoveInput, error) {
+	if levelIsWon(level) {
+		return []MoveInput{}, nil
+	}
+	if dead, reasons := IsDeadState(level); dead {
+		return nil, deadStateError(reasons)
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	visited := newVisitedSet()
+	visited.add(CanonicalBytes(level), 0)
+	frontier := []frontierEntry{{level: copyLevel(level), node: &parallelNode{}}}
+	var depth, frontierSize atomic.Int64
+	frontierSize.Store(1)
+
+	if opts.Progress != nil {
+		interval := opts.ProgressInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-ticker.C:
+					opts.Progress(SolveProgress{
+						Visited:  int(visited.size.Load()),
+						Frontier: int(frontierSize.Load()),
+						Depth:    int(depth.Load()),
+					})
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	for len(frontier) > 0 {
+		if opts.MaxDepth > 0 && int(depth.Load()) >= opts.MaxDepth {
+			return nil, fmt.Errorf("%w: no solution within %d moves (visited %d states)", ErrSearchLimitReached, opts.MaxDepth, visited.size.Load())
+		}
+
+		// Each worker takes an interleaved share of the frontier,
+		// and produces its own share of the next frontier.
+		nextFrontiers := make([][]frontierEntry, workers)
+		winners := make([]*parallelNode, workers)
+		var found, limitReached atomic.Bool
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := w; i < len(frontier); i += workers {
+					if found.Load() || limitReached.Load() || ctx.Err() != nil {
+						return
+					}
+					entry := frontier[i]
+					for _, successor := range successors(entry.level) {
+						added, full := visited.add(CanonicalBytes(successor.level), opts.MaxStates)
+						if full {
+							limitReached.Store(true)
+							return
+						}
+						if !added {
+							continue
+						}
+						node := &parallelNode{parent: entry.node, moveInput: successor.moveInput}
+						if levelIsWon(successor.level) {
+							winners[w] = node
+							found.Store(true)
+							return
+						}
+						if isDeadState(successor.level) {
+							continue
+						}
+						nextFrontiers[w] = append(nextFrontiers[w], frontierEntry{level: successor.level, node: node})
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		depth.Add(1)
+
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("search cancelled at depth %d: %w", depth.Load(), err)
+		}
+		// Any solution found at this depth is a shortest one.
+		for _, winner := range winners {
+			if winner != nil {
+				return winner.solution(int(depth.Load())), nil
+			}
+		}
+		if limitReached.Load() {
+			return nil, fmt.Errorf("%w: visited %d states without finding a solution", ErrSearchLimitReached, visited.size.Load())
+		}
+
+		frontier = frontier[:0]
+		for _, next := range nextFrontiers {
+			frontier = append(frontier, next...)
+		}
+		frontierSize.Store(int64(len(frontier)))
+	}
+
+	return nil, fmt.Errorf("%w: exhausted all %d reachable states", ErrUnsolvable, visited.size.Load())
+}
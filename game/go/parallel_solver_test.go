@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSolveParallelMatchesSolve(t *testing.T) {
+	for _, levelId := range []string{
+		"levels/easy/001-movement.json",
+		"levels/tests/get-stuck-by-moving-down.json",
+		"levels/tests/generated-level.json",
+		"levels/tests/only-one-snake-gets-stuck-by-moving-down.json",
+	} {
+		level, err := LoadLevel(levelId)
+		if err != nil {
+			t.Fatalf("Failed to load level %s: %v", levelId, err)
+		}
+		expected, err := Solve(level, SolveOptions{})
+		if err != nil {
+			t.Fatalf("Failed to solve level %s: %v", levelId, err)
+		}
+		var progressReports atomic.Int32
+		solution, err := SolveParallel(context.Background(), level, ParallelSolveOptions{
+			Workers:          4,
+			Progress:         func(SolveProgress) { progressReports.Add(1) },
+			ProgressInterval: time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("Failed to solve level %s in parallel: %v", levelId, err)
+		}
+		if len(solution) != len(expected) {
+			t.Errorf("%s: expected a %d move solution, but got %d moves: %s", levelId, len(expected), len(solution), String(solution))
+		}
+		for i, input := range solution {
+			move := AnalyzeMoveRelative(getSnakeByID(input.SnakeID, level), input.Direction.X, input.Direction.Y, level)
+			if !move.Valid {
+				t.Fatalf("%s: move %d of solution is invalid", levelId, i)
+			}
+			TakeMove(move, level)
+		}
+		if !levelIsWon(level) {
+			t.Errorf("%s: expected solution to win the level", levelId)
+		}
+	}
+}
+
+func TestSolveParallelCancellation(t *testing.T) {
+	level, err := LoadLevel("levels/easy/001-movement.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = SolveParallel(ctx, level, ParallelSolveOptions{Workers: 2})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, but got %v", err)
+	}
+}
+
+func TestSolveParallelUnsolvable(t *testing.T) {
+	level := &Level{
+		Info:     LevelInfo{Width: 3, Height: 1},
+		Grid:     [][]CollisionLayer{{Black, Black, Black}},
+		Entities: []Entity{&Snake{ID: "a", Layer: White, Segments: []Point{{X: 0, Y: 0}}}, &Food{Position: Point{X: 2, Y: 0}, Layer: Black}},
+	}
+	_, err := SolveParallel(context.Background(), level, ParallelSolveOptions{Workers: 2})
+	if !errors.Is(err, ErrUnsolvable) {
+		t.Errorf("Expected ErrUnsolvable, but got %v", err)
+	}
+}
+
+func TestSolveParallelMaxStatesMatchesSolve(t *testing.T) {
+	level, err := LoadLevel("levels/tests/generated-level.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	_, expected := Solve(level, SolveOptions{MaxStates: 5})
+	if !errors.Is(expected, ErrSearchLimitReached) {
+		t.Fatalf("Expected Solve to reach the limit, but got %v", expected)
+	}
+	_, err = SolveParallel(context.Background(), level, ParallelSolveOptions{SolveOptions: SolveOptions{MaxStates: 5}, Workers: 4})
+	if !errors.Is(err, ErrSearchLimitReached) || err.Error() != expected.Error() {
+		t.Errorf("Expected %q, but got %v", expected, err)
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+)
+
+// Equivalent to PLAYTHROUGH_FORMAT_VERSION in shared-helpers.ts
+const PlaythroughFormatVersion = 2
+
+var (
+	ErrInvalidPlaythroughFormat        = errors.New("invalid playthrough format")
+	ErrPlaythroughFormatVersionTooNew  = errors.New("playthrough format version is too new")
+	ErrInvalidPlaythroughFormatVersion = errors.New("invalid playthrough format version")
+)
+
+// Equivalent to the object written by serializePlaythrough() in game-state.ts
+type SnakeshiftPlaythroughFormat struct {
+	Format        string        `json:"format"`
+	FormatVersion int           `json:"formatVersion"`
+	BaseState     interface{}   `json:"baseState"`
+	Deltas        []interface{} `json:"deltas"`
+}
+
+// Equivalent to parsePlaythrough() in shared-helpers.ts,
+// but returns the states as generic JSON values rather than strings.
+func parsePlaythroughStates(data []byte) ([]map[string]interface{}, error) {
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+
+	if stateStrings, ok := parsed.([]interface{}); ok {
+		// V1: The first version of the playthrough format was just an array of JSON strings
+		// containing snakeshift level file data. No need to convert it to deltas just to apply them again.
+		states := make([]map[string]interface{}, 0, len(stateStrings))
+		for i, stateString := range stateStrings {
+			str, ok := stateString.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: state %d is not a string", ErrInvalidPlaythroughFormat, i)
+			}
+			var state map[string]interface{}
+			if err := json.Unmarshal([]byte(str), &state); err != nil {
+				return nil, fmt.Errorf("state %d: %w", i, err)
+			}
+			states = append(states, state)
+		}
+		return states, nil
+	}
+
+	var playthrough SnakeshiftPlaythroughFormat
+	if err := json.Unmarshal(data, &playthrough); err != nil {
+		return nil, err
+	}
+	if playthrough.Format != "snakeshift-playthrough" {
+		return nil, fmt.Errorf("%w: expected \"snakeshift-playthrough\", got %q", ErrInvalidPlaythroughFormat, playthrough.Format)
+	}
+	if playthrough.FormatVersion > PlaythroughFormatVersion {
+		return nil, fmt.Errorf("%w: %d (newest supported is %d)", ErrPlaythroughFormatVersionTooNew, playthrough.FormatVersion, PlaythroughFormatVersion)
+	}
+	if playthrough.FormatVersion != PlaythroughFormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidPlaythroughFormatVersion, playthrough.FormatVersion)
+	}
+	state, ok := playthrough.BaseState.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: missing \"baseState\" property", ErrInvalidPlaythroughFormat)
+	}
+	if playthrough.Deltas == nil {
+		return nil, fmt.Errorf("%w: missing \"deltas\" property", ErrInvalidPlaythroughFormat)
+	}
+
+	states := []map[string]interface{}{state}
+	for i, delta := range playthrough.Deltas {
+		patched, _, err := patchJSON(deepCopyJSON(state), delta)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply delta %d: %w", i, err)
+		}
+		state, ok = patched.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: delta %d did not produce an object", ErrInvalidPlaythroughFormat, i)
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// activeSnakeIDOfState returns the ID of the snake at activePlayerEntityIndex, or "" if there is none.
+func activeSnakeIDOfState(state map[string]interface{}) string {
+	index, ok := jsonInt(state["activePlayerEntityIndex"])
+	if !ok || index < 0 {
+		return ""
+	}
+	entities, _ := state["entities"].([]interface{})
+	if index >= len(entities) {
+		return ""
+	}
+	entity, _ := entities[index].(map[string]interface{})
+	id, _ := entity["id"].(string)
+	return id
+}
+
+// DeserializePlaythrough returns the sequence of level states in a playthrough,
+// and the moves between them, such that moveInputs[i] leads from states[i] to states[i+1].
+// States where nothing but the active snake changed are skipped.
+// See also getMovesFromPlaythrough() in shared-helpers.ts
+func DeserializePlaythrough(data []byte) ([]*Level, []MoveInput, error) {
+	rawStates, err := parsePlaythroughStates(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var states []*Level
+	var moveInputs []MoveInput
+	activeSnakeID := ""
+	// Going back to an earlier state, by undoing, redoing, or restarting the level, abandons the moves since then,
+	// but only once play continues from there, since a recording can end by reloading the level
+	// after the final move, which wasn't recorded. See below.
+	rewindTo := -1
+	for i, rawState := range rawStates {
+		// Playthroughs may include the initial state of the next level, hopefully always with no active snake.
+		stateActiveSnakeID := activeSnakeIDOfState(rawState)
+		if stateActiveSnakeID == "" {
+			continue
+		}
+		// Playthroughs may also include the initial state of the next level with an active snake,
+		// but nothing after the winning state is part of this level.
+		if len(states) > 0 && levelIsWon(states[len(states)-1]) {
+			break
+		}
+		activeSnakeID = stateActiveSnakeID
+		stateJSON, err := json.Marshal(rawState)
+		if err != nil {
+			return nil, nil, err
+		}
+		level, err := DeserializeLevel(stateJSON)
+		if err != nil {
+			return nil, nil, fmt.Errorf("state %d: %w", i, err)
+		}
+		if len(states) == 0 {
+			states = append(states, level)
+			continue
+		}
+		prevLevel := states[len(states)-1]
+		if rewindTo != -1 {
+			prevLevel = states[rewindTo]
+		}
+		if Equal(prevLevel, level) {
+			continue // switching snakes
+		}
+		// Check for an earlier state first, since undoing a move can look like a move backwards.
+		if earlier := slices.IndexFunc(states, func(state *Level) bool { return Equal(state, level) }); earlier != -1 {
+			if rewindTo != -1 && earlier > rewindTo {
+				// Redoing, or making the same moves again, continues play from the earlier state.
+				states = states[:earlier+1]
+				moveInputs = moveInputs[:earlier]
+				rewindTo = -1
+			} else {
+				rewindTo = earlier
+			}
+			continue
+		}
+		moveInput, ok := moveInputBetween(prevLevel, level, activeSnakeID)
+		if !ok {
+			return nil, nil, fmt.Errorf("state %d: could not determine move from previous state", i)
+		}
+		if rewindTo != -1 {
+			states = states[:rewindTo+1]
+			moveInputs = moveInputs[:rewindTo]
+			rewindTo = -1
+		}
+		states = append(states, level)
+		moveInputs = append(moveInputs, moveInput)
+	}
+	if len(states) == 0 {
+		return nil, nil, fmt.Errorf("%w: no states with an active snake", ErrInvalidPlaythroughFormat)
+	}
+
+	// Playthrough might not contain the final state/move (awkward)
+	// However, if that's the case, there should only be one Food left,
+	// so we can just compare its position to the active snake's head in the last state.
+	lastLevel := states[len(states)-1]
+	var foods []*Food
+	for _, entity := range lastLevel.Entities {
+		if food, ok := entity.(*Food); ok {
+			foods = append(foods, food)
+		}
+	}
+	if len(foods) == 1 {
+		for _, snake := range getSnakes(lastLevel) {
+			if snake.ID != activeSnakeID {
+				continue
+			}
+			head := snake.Segments[0]
+			direction := Point{X: sign(foods[0].Position.X - head.X), Y: sign(foods[0].Position.Y - head.Y)}
+			if direction.X != 0 {
+				direction.Y = 0
+			}
+			level := copyLevel(lastLevel)
+			move := AnalyzeMoveRelative(getSnakeByID(activeSnakeID, level), direction.X, direction.Y, level)
+			if !move.Valid {
+				break
+			}
+			TakeMove(move, level)
+			if levelIsWon(level) {
+				states = append(states, level)
+				moveInputs = append(moveInputs, MoveToMoveInput(move))
+			}
+		}
+	}
+
+	return states, moveInputs, nil
+}
+
+// moveInputBetween figures out the move from the difference between states.
+// If multiple snakes moved (e.g. fused snakes), the active snake is preferred.
+// Snakes that jumped more than one tile are not considered to have moved,
+// so that a recording that skips a state is reported, rather than guessed at.
+func moveInputBetween(before, after *Level, activeSnakeID string) (MoveInput, bool) {
+	var found *MoveInput
+	for _, snakeAfter := range getSnakes(after) {
+		for _, snakeBefore := range getSnakes(before) {
+			if snakeBefore.ID != snakeAfter.ID || len(snakeBefore.Segments) == 0 || len(snakeAfter.Segments) == 0 {
+				continue
+			}
+			headBefore := snakeBefore.Segments[0]
+			headAfter := snakeAfter.Segments[0]
+			direction := Point{X: headAfter.X - headBefore.X, Y: headAfter.Y - headBefore.Y}
+			if abs(direction.X)+abs(direction.Y) != 1 {
+				continue
+			}
+			if snakeAfter.ID == activeSnakeID || found == nil {
+				found = &MoveInput{Direction: direction, SnakeID: snakeAfter.ID}
+			}
+		}
+	}
+	if found == nil {
+		return MoveInput{}, false
+	}
+	return *found, true
+}
+
+func levelToFormatWithActiveSnake(level *Level, activeSnakeID string) SnakeshiftLevelFormat {
+	levelFormat := levelToFormat(level)
+	for i, entity := range levelFormat.Entities {
+		if snake, ok := entity.(EntitySnake); ok && snake.ID == activeSnakeID {
+			levelFormat.ActivePlayerEntityIndex = i
+		}
+	}
+	return levelFormat
+}
+
+// levelToPlaythroughState converts a level to a generic JSON value for diffing,
+// with the given snake marked as active.
+func levelToPlaythroughState(level *Level, activeSnakeID string) (interface{}, error) {
+	levelJSON, err := json.Marshal(levelToFormatWithActiveSnake(level, activeSnakeID))
+	if err != nil {
+		return nil, err
+	}
+	var state interface{}
+	if err := json.Unmarshal(levelJSON, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// SerializePlaythrough writes a playthrough in the same format as serializePlaythrough() in game-state.ts,
+// so that it can be replayed in the web version of the game.
+func SerializePlaythrough(level *Level, moveInputs []MoveInput) ([]byte, error) {
+	activeSnakeID := ""
+	if len(moveInputs) > 0 {
+		activeSnakeID = moveInputs[0].SnakeID
+	} else if snakes := getSnakes(level); len(snakes) > 0 {
+		activeSnakeID = snakes[0].ID
+	}
+	baseState, err := levelToPlaythroughState(level, activeSnakeID)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas := make([]interface{}, 0, len(moveInputs))
+	prevState := baseState
+	currentLevel := copyLevel(level)
+	for i, input := range moveInputs {
+		var snake *Snake
+		for _, s := range getSnakes(currentLevel) {
+			if s.ID == input.SnakeID {
+				snake = s
+			}
+		}
+		if snake == nil {
+			return nil, fmt.Errorf("move %d: no snake found with ID '%s'", i, input.SnakeID)
+		}
+		move := AnalyzeMoveRelative(snake, input.Direction.X, input.Direction.Y, currentLevel)
+		if !move.Valid {
+			return nil, fmt.Errorf("move %d: invalid move input: snake ID '%s', direction (%d, %d)", i, input.SnakeID, input.Direction.X, input.Direction.Y)
+		}
+		TakeMove(move, currentLevel)
+		state, err := levelToPlaythroughState(currentLevel, input.SnakeID)
+		if err != nil {
+			return nil, err
+		}
+		deltas = append(deltas, diffJSON(prevState, state))
+		prevState = state
+	}
+
+	return json.Marshal(SnakeshiftPlaythroughFormat{
+		Format:        "snakeshift-playthrough",
+		FormatVersion: PlaythroughFormatVersion,
+		BaseState:     levelToFormatWithActiveSnake(level, activeSnakeID),
+		Deltas:        deltas,
+	})
+}
+
+func LoadPlaythrough(playthroughId string) ([]*Level, []MoveInput, error) {
+	if playthroughId == "" {
+		return nil, nil, fmt.Errorf("playthroughId cannot be empty")
+	}
+	playthroughJSON, err := os.ReadFile(path.Join("..", "public", playthroughId))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read playthrough file %s: %w", playthroughId, err)
+	}
+	states, moveInputs, err := DeserializePlaythrough(playthroughJSON)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load playthrough %s: %w", playthroughId, err)
+	}
+	return states, moveInputs, nil
+}
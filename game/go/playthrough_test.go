@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLoadPlaythrough(t *testing.T) {
+	states, moveInputs, err := LoadPlaythrough("levels/easy/001-movement-playthrough.json")
+	if err != nil {
+		t.Fatalf("Failed to load playthrough: %v", err)
+	}
+	if len(states) != len(moveInputs)+1 {
+		t.Fatalf("Expected one more state than moves, but got %d states and %d moves", len(states), len(moveInputs))
+	}
+	if !levelIsWon(states[len(states)-1]) {
+		t.Errorf("Expected the last state of the playthrough to be won")
+	}
+	// The states should be reproducible by replaying the moves.
+	level := copyLevel(states[0])
+	for i, input := range moveInputs {
+		move := AnalyzeMoveRelative(getSnakeByID(input.SnakeID, level), input.Direction.X, input.Direction.Y, level)
+		if !move.Valid {
+			t.Fatalf("Move %d is invalid: %v", i, input)
+		}
+		TakeMove(move, level)
+		if !Equal(level, states[i+1]) {
+			t.Fatalf("State after move %d doesn't match the playthrough", i)
+		}
+	}
+}
+
+func TestSerializePlaythroughRoundTrip(t *testing.T) {
+	originalStates, originalMoveInputs, err := LoadPlaythrough("levels/easy/002-switching-snakes-playthrough.json")
+	if err != nil {
+		t.Fatalf("Failed to load playthrough: %v", err)
+	}
+	serialized, err := SerializePlaythrough(originalStates[0], originalMoveInputs)
+	if err != nil {
+		t.Fatalf("Failed to serialize playthrough: %v", err)
+	}
+	states, moveInputs, err := DeserializePlaythrough(serialized)
+	if err != nil {
+		t.Fatalf("Failed to deserialize playthrough: %v", err)
+	}
+	if !reflect.DeepEqual(moveInputs, originalMoveInputs) {
+		t.Errorf("Moves didn't round-trip.\nExpected:\n  %v\nActual:\n  %v", String(originalMoveInputs), String(moveInputs))
+	}
+	if len(states) != len(originalStates) {
+		t.Fatalf("Expected %d states, but got %d", len(originalStates), len(states))
+	}
+	for i := range states {
+		if !Equal(states[i], originalStates[i]) {
+			t.Errorf("State %d didn't round-trip", i)
+		}
+	}
+}
+
+// v1Playthrough builds a playthrough in the first version of the format, an array of JSON strings of states,
+// so tests can arrange states freely.
+func v1Playthrough(t *testing.T, states []*Level, activeSnakeID string) []byte {
+	var stateStrings []string
+	for _, state := range states {
+		stateJSON, err := json.Marshal(levelToFormatWithActiveSnake(state, activeSnakeID))
+		if err != nil {
+			t.Fatalf("Failed to serialize state: %v", err)
+		}
+		stateStrings = append(stateStrings, string(stateJSON))
+	}
+	data, err := json.Marshal(stateStrings)
+	if err != nil {
+		t.Fatalf("Failed to serialize playthrough: %v", err)
+	}
+	return data
+}
+
+func TestDeserializePlaythroughRestarts(t *testing.T) {
+	s, moveInputs, err := LoadPlaythrough("levels/easy/001-movement-playthrough.json")
+	if err != nil {
+		t.Fatalf("Failed to load playthrough: %v", err)
+	}
+	snakeID := moveInputs[0].SnakeID
+	// Undoing the second move and making a different one.
+	var alternative MoveInput
+	var alternativeState *Level
+	for _, direction := range CardinalDirections {
+		input := MoveInput{Direction: direction, SnakeID: snakeID}
+		if state, ok := applyMoveInputs(s[1], input); ok && !Equal(state, s[0]) && !Equal(state, s[2]) {
+			alternative, alternativeState = input, state
+			break
+		}
+	}
+	if alternativeState == nil {
+		t.Fatalf("Expected another move to be possible after the first")
+	}
+	for _, test := range []struct {
+		name          string
+		states        []*Level
+		expectedMoves []MoveInput
+	}{
+		{"restart", []*Level{s[0], s[1], s[2], s[0], s[1]}, moveInputs[:1]},
+		{"undo", []*Level{s[0], s[1], s[2], s[1], alternativeState}, []MoveInput{moveInputs[0], alternative}},
+		{"undo several moves", []*Level{s[0], s[1], s[2], s[3], s[1], s[2]}, moveInputs[:2]},
+		{"undo and redo", []*Level{s[0], s[1], s[2], s[1], s[2], s[3]}, moveInputs[:3]},
+		{"reloaded after the last move", []*Level{s[0], s[1], s[2], s[0]}, moveInputs[:2]},
+	} {
+		_, decoded, err := DeserializePlaythrough(v1Playthrough(t, test.states, snakeID))
+		if err != nil {
+			t.Errorf("%s: failed to deserialize playthrough: %v", test.name, err)
+		} else if !reflect.DeepEqual(decoded, test.expectedMoves) {
+			t.Errorf("%s: expected moves %v, but got %v", test.name, String(test.expectedMoves), String(decoded))
+		}
+	}
+
+	// A jump that no move explains shouldn't silently cut the playthrough short.
+	if _, _, err := DeserializePlaythrough(v1Playthrough(t, []*Level{s[0], s[1], s[3], s[4]}, snakeID)); err == nil {
+		t.Errorf("Expected an error for a state that no move leads to")
+	}
+}
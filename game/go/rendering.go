@@ -2,7 +2,9 @@ package main
 
 import (
 	"math"
+	"strconv"
 	"time"
+	"unicode/utf8"
 
 	"github.com/nsf/termbox-go"
 )
@@ -54,6 +56,12 @@ func render(g *Game) {
 	tbPrint(0, 0, termbox.ColorWhite, termbox.ColorBlack, "Snake")
 	tbPrint(5, 0, termbox.ColorBlack, termbox.ColorWhite, "Shift")
 	tbPrint(11, 0, termbox.ColorWhite, termbox.ColorBlack, "- "+g.levelName)
+	// Move count, and par if known
+	par := "?"
+	if g.level.Info.Par > 0 {
+		par = strconv.Itoa(g.level.Info.Par)
+	}
+	tbPrint(15+utf8.RuneCountInString(g.levelName), 0, termbox.ColorWhite, termbox.ColorBlack, "Moves: "+strconv.Itoa(g.moves)+" / "+par)
 	// Draw the game board
 	for y := 0; y < g.level.Info.Height; y++ {
 		for x := 0; x < g.level.Info.Width; x++ {
@@ -162,13 +170,15 @@ func render(g *Game) {
 		entity.Draw(g)
 	}
 
-	// Show level stuck hint
-	if len(getAllPossibleMoves(g.level)) == 0 {
+	// Show level stuck hint, including when the level can't be won even though snakes can still move
+	if dead, reasons := deadState(g); dead {
 		borderHeight := 1
 		if unicode {
 			borderHeight = fancyBorderSliceY
 		}
-		tbPrint(0, boardStartY+g.level.Info.Height*cellHeight+borderHeight, termbox.ColorWhite, termbox.ColorBlack, "Press 'Z' to undo or 'R' to restart the level.")
+		hintY := boardStartY + g.level.Info.Height*cellHeight + borderHeight
+		tbPrint(0, hintY, termbox.ColorWhite, termbox.ColorBlack, "This level can no longer be won: "+reasons[0].Message+".")
+		tbPrint(0, hintY+1, termbox.ColorWhite, termbox.ColorBlack, "Press 'Z' to undo or 'R' to restart the level.")
 	}
 
 	termbox.Flush()
@@ -294,3 +304,81 @@ func (snake *Snake) Draw(g *Game) {
 		}
 	}
 }
+
+func (crate *Crate) Draw(g *Game) {
+	x := boardStartX + crate.Position.X*cellWidth
+	y := boardStartY + crate.Position.Y*cellHeight
+	bg := termbox.ColorRed
+	fg := termbox.ColorRed
+	switch crate.Layer {
+	case White:
+		bg = termbox.ColorWhite
+		fg = termbox.ColorBlack
+	case Black:
+		bg = termbox.ColorBlack
+		fg = termbox.ColorWhite
+	}
+	chars := []rune("[]")
+	if unicode {
+		chars = []rune("[╳]")
+	}
+	for charY := 0; charY < cellHeight; charY++ {
+		for charX := 0; charX < cellWidth; charX++ {
+			ch := ' '
+			if charX < len(chars) {
+				ch = chars[charX]
+			}
+			termbox.SetCell(x+charX, y+charY, ch, fg, bg)
+		}
+	}
+}
+
+func (inverter *Inverter) Draw(g *Game) {
+	x := boardStartX + inverter.Position.X*cellWidth
+	y := boardStartY + inverter.Position.Y*cellHeight
+	for charY := 0; charY < cellHeight; charY++ {
+		for charX := 0; charX < cellWidth; charX++ {
+			colorUnder := termbox.GetCell(x+charX, y+charY).Bg
+			fg := termbox.ColorWhite
+			if colorUnder == termbox.ColorWhite {
+				fg = termbox.ColorBlack
+			}
+			if unicode {
+				if charX == 1 {
+					termbox.SetCell(x+charX, y+charY, '☯', fg, colorUnder)
+				}
+			} else if charX == 0 {
+				termbox.SetCell(x+charX, y+charY, '%', fg, colorUnder)
+			}
+		}
+	}
+}
+
+func (cell *CellularAutomata) Draw(g *Game) {
+	x := boardStartX + cell.Position.X*cellWidth
+	y := boardStartY + cell.Position.Y*cellHeight
+	fg := termbox.ColorRed
+	switch cell.Layer {
+	case White:
+		fg = termbox.ColorWhite
+	case Black:
+		fg = termbox.ColorBlack
+	}
+	// Pulse like the TS version's animated lobes
+	t := float64(time.Now().UnixMilli())/1000.0 + float64(cell.Position.X+cell.Position.Y)*0.3
+	ch := '*'
+	if unicode {
+		ch = '✲'
+		if math.Sin(t) < 0 {
+			ch = '✱'
+		}
+	}
+	for charY := 0; charY < cellHeight; charY++ {
+		for charX := 0; charX < cellWidth; charX++ {
+			colorUnder := termbox.GetCell(x+charX, y+charY).Bg
+			if charX == cellWidth/2 {
+				termbox.SetCell(x+charX, y+charY, ch, fg, colorUnder)
+			}
+		}
+	}
+}
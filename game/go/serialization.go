@@ -1,6 +1,19 @@
 package main
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Equivalent to LEVEL_FORMAT_VERSION in shared-helpers.ts
+const LevelFormatVersion = 6
+
+var (
+	ErrInvalidFormat        = errors.New("invalid format")
+	ErrFormatVersionTooNew  = errors.New("format version is too new")
+	ErrInvalidFormatVersion = errors.New("invalid format version")
+)
 
 // Equivalent to interface ParsedGameState in types.ts
 type SnakeshiftLevelFormat struct {
@@ -31,6 +44,7 @@ type EntitySnake struct {
 	ID             string         `json:"id"`
 	Segments       []SnakeSegment `json:"segments"`
 	GrowOnNextMove bool           `json:"growOnNextMove"`
+	FusedSnakeIDs  []string       `json:"fusedSnakeIds,omitempty"` // too weird of a feature to include in all level files
 }
 
 type EntityFood struct {
@@ -41,6 +55,30 @@ type EntityFood struct {
 	Layer  CollisionLayer `json:"layer"`
 }
 
+type EntityInverter struct {
+	X      int            `json:"x"`
+	Y      int            `json:"y"`
+	Width  int            `json:"width"`
+	Height int            `json:"height"`
+	Layer  CollisionLayer `json:"layer"`
+}
+
+type EntityCellularAutomata struct {
+	X      int            `json:"x"`
+	Y      int            `json:"y"`
+	Width  int            `json:"width"`
+	Height int            `json:"height"`
+	Layer  CollisionLayer `json:"layer"`
+}
+
+type EntityCrate struct {
+	X      int            `json:"x"`
+	Y      int            `json:"y"`
+	Width  int            `json:"width"`
+	Height int            `json:"height"`
+	Layer  CollisionLayer `json:"layer"`
+}
+
 type EntityBlock struct {
 	X      int            `json:"x"`
 	Y      int            `json:"y"`
@@ -64,6 +102,10 @@ func pointsToSnakeSegments(points []Point, layer CollisionLayer) []SnakeSegment
 }
 
 func SerializeLevel(level *Level) ([]byte, error) {
+	return json.MarshalIndent(levelToFormat(level), "", "  ")
+}
+
+func levelToFormat(level *Level) SnakeshiftLevelFormat {
 	var entities []interface{}
 	var entityTypes []string
 
@@ -98,11 +140,42 @@ func SerializeLevel(level *Level) ([]byte, error) {
 			}
 			entities = append(entities, ent)
 			entityTypes = append(entityTypes, "Food")
+		case *Inverter:
+			ent := EntityInverter{
+				X:      e.Position.X,
+				Y:      e.Position.Y,
+				Width:  1,
+				Height: 1,
+				Layer:  e.Layer,
+			}
+			entities = append(entities, ent)
+			entityTypes = append(entityTypes, "Inverter")
+		case *CellularAutomata:
+			ent := EntityCellularAutomata{
+				X:      e.Position.X,
+				Y:      e.Position.Y,
+				Width:  1,
+				Height: 1,
+				Layer:  e.Layer,
+			}
+			entities = append(entities, ent)
+			entityTypes = append(entityTypes, "CellularAutomata")
+		case *Crate:
+			ent := EntityCrate{
+				X:      e.Position.X,
+				Y:      e.Position.Y,
+				Width:  1,
+				Height: 1,
+				Layer:  e.Layer,
+			}
+			entities = append(entities, ent)
+			entityTypes = append(entityTypes, "Crate")
 		case *Snake:
 			ent := EntitySnake{
 				ID:             e.ID,
 				Segments:       pointsToSnakeSegments(e.Segments, e.Layer),
 				GrowOnNextMove: e.GrowOnNextMove,
+				FusedSnakeIDs:  validFusedSnakeIDs(e, level),
 			}
 			entities = append(entities, ent)
 			entityTypes = append(entityTypes, "Snake")
@@ -110,16 +183,14 @@ func SerializeLevel(level *Level) ([]byte, error) {
 	}
 
 	// Construct final game state
-	gameState := SnakeshiftLevelFormat{
+	return SnakeshiftLevelFormat{
 		Format:                  "snakeshift",
-		FormatVersion:           6,
+		FormatVersion:           LevelFormatVersion,
 		LevelInfo:               level.Info,
 		Entities:                entities,
 		EntityTypes:             entityTypes,
 		ActivePlayerEntityIndex: -1,
 	}
-
-	return json.MarshalIndent(gameState, "", "  ")
 }
 
 // func DeserializeLevel(data []byte) (*Level, error) {
@@ -180,18 +251,105 @@ func SerializeLevel(level *Level) ([]byte, error) {
 
 // 	return level, nil
 // }
+
+// Upgrade save format, version by version
+// Equivalent to the upgrade steps in deserialize() in game-state.ts
+func upgradeLevelFormat(levelFormat *SnakeshiftLevelFormat) error {
+	if levelFormat.Format != "snakeshift" {
+		return fmt.Errorf("%w: expected \"snakeshift\", got %q", ErrInvalidFormat, levelFormat.Format)
+	}
+	if levelFormat.FormatVersion > LevelFormatVersion {
+		return fmt.Errorf("%w: %d (newest supported is %d)", ErrFormatVersionTooNew, levelFormat.FormatVersion, LevelFormatVersion)
+	}
+	if levelFormat.FormatVersion == 1 {
+		levelFormat.FormatVersion = 2
+		for i, entity := range levelFormat.Entities {
+			if i >= len(levelFormat.EntityTypes) || levelFormat.EntityTypes[i] != "Snake" {
+				continue
+			}
+			entityMap, ok := entity.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			segments, _ := entityMap["segments"].([]interface{})
+			for _, segment := range segments {
+				segmentMap, ok := segment.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if _, hasWidth := segmentMap["width"]; hasWidth {
+					continue // actually already updated data shape before bumping version, so handle that
+				}
+				segmentMap["width"] = segmentMap["size"]
+				segmentMap["height"] = segmentMap["size"]
+			}
+		}
+	}
+	if levelFormat.FormatVersion == 2 {
+		levelFormat.FormatVersion = 3
+		// Levels now store their size in the levelInfo object.
+		// This is the historical default size, not necessarily the current default.
+		levelFormat.LevelInfo = LevelInfo{Width: 16, Height: 16}
+	}
+	if levelFormat.FormatVersion == 3 {
+		levelFormat.FormatVersion = 4
+		// Remove accidentally serialized _time properties from Collectable entities
+		for _, entity := range levelFormat.Entities {
+			if entityMap, ok := entity.(map[string]interface{}); ok {
+				delete(entityMap, "_time")
+			}
+		}
+	}
+	if levelFormat.FormatVersion == 4 {
+		levelFormat.FormatVersion = 5
+		// Rename "Collectable" to "Food"
+		for i := range levelFormat.EntityTypes {
+			if levelFormat.EntityTypes[i] == "Collectable" {
+				levelFormat.EntityTypes[i] = "Food"
+			}
+		}
+	}
+	if levelFormat.FormatVersion == 5 {
+		levelFormat.FormatVersion = 6
+		// Remove accidentally serialized _time and solid properties from Collectable entities
+		for i, entity := range levelFormat.Entities {
+			if i >= len(levelFormat.EntityTypes) || (levelFormat.EntityTypes[i] != "Food" && levelFormat.EntityTypes[i] != "Inverter") {
+				continue
+			}
+			if entityMap, ok := entity.(map[string]interface{}); ok {
+				delete(entityMap, "_time")
+				delete(entityMap, "solid")
+			}
+		}
+	}
+	if levelFormat.FormatVersion != LevelFormatVersion {
+		return fmt.Errorf("%w: %d", ErrInvalidFormatVersion, levelFormat.FormatVersion)
+	}
+	return nil
+}
+
 func DeserializeLevel(data []byte) (*Level, error) {
-	// TODO: check format and version
 	var levelFormat SnakeshiftLevelFormat
 	if err := json.Unmarshal(data, &levelFormat); err != nil {
 		return nil, err
 	}
+	if err := upgradeLevelFormat(&levelFormat); err != nil {
+		return nil, err
+	}
 
 	level := &Level{
 		Info:     levelFormat.LevelInfo,
 		Entities: []Entity{},
 	}
 
+	if levelFormat.LevelInfo.Width <= 0 || levelFormat.LevelInfo.Height <= 0 {
+		// Checked before allocating the grid, which would panic with a negative size.
+		return nil, &ValidationError{Diagnostics: []Diagnostic{{
+			EntityIndex: -1,
+			Message:     fmt.Sprintf("invalid level size %dx%d", levelFormat.LevelInfo.Width, levelFormat.LevelInfo.Height),
+		}}}
+	}
+
 	// Initialize grid
 	grid := make([][]CollisionLayer, levelFormat.LevelInfo.Height)
 	for y := range grid {
@@ -201,8 +359,18 @@ func DeserializeLevel(data []byte) (*Level, error) {
 		}
 	}
 
+	// Problems that can't be represented in the loaded level are reported here,
+	// while the rest are left to Validate.
+	var diagnostics []Diagnostic
+	if len(levelFormat.Entities) != len(levelFormat.EntityTypes) {
+		diagnostics = append(diagnostics, Diagnostic{
+			EntityIndex: -1,
+			Message:     fmt.Sprintf("%d entities but %d entity types", len(levelFormat.Entities), len(levelFormat.EntityTypes)),
+		})
+	}
+
 	// Process entities
-	for i, typ := range levelFormat.EntityTypes {
+	for i, typ := range levelFormat.EntityTypes[:min(len(levelFormat.EntityTypes), len(levelFormat.Entities))] {
 		raw := levelFormat.Entities[i]
 		mapped, err := json.Marshal(raw)
 		if err != nil {
@@ -215,9 +383,16 @@ func DeserializeLevel(data []byte) (*Level, error) {
 			if err := json.Unmarshal(mapped, &block); err != nil {
 				return nil, err
 			}
-			for w := 0; w < block.Width; w++ {
-				for h := 0; h < block.Height; h++ {
-					grid[block.Y+h][block.X+w] = block.Layer
+			// Only the part of the block within the level is filled, so that a huge block can't hang loading.
+			left, top := max(block.X, 0), max(block.Y, 0)
+			right, bottom := min(block.X+block.Width, level.Info.Width), min(block.Y+block.Height, level.Info.Height)
+			outOfBounds := left != block.X || top != block.Y || right != block.X+block.Width || bottom != block.Y+block.Height
+			if block.Width > 0 && block.Height > 0 && outOfBounds {
+				diagnostics = append(diagnostics, diagnosticAt(Point{X: block.X, Y: block.Y}, i, "Block is out of bounds (%dx%d)", block.Width, block.Height))
+			}
+			for y := top; y < bottom; y++ {
+				for x := left; x < right; x++ {
+					grid[y][x] = block.Layer
 				}
 			}
 
@@ -231,6 +406,36 @@ func DeserializeLevel(data []byte) (*Level, error) {
 				Layer:    food.Layer,
 			})
 
+		case "Inverter":
+			var inverter EntityInverter
+			if err := json.Unmarshal(mapped, &inverter); err != nil {
+				return nil, err
+			}
+			level.Entities = append(level.Entities, &Inverter{
+				Position: Point{X: inverter.X, Y: inverter.Y},
+				Layer:    inverter.Layer,
+			})
+
+		case "CellularAutomata":
+			var cell EntityCellularAutomata
+			if err := json.Unmarshal(mapped, &cell); err != nil {
+				return nil, err
+			}
+			level.Entities = append(level.Entities, &CellularAutomata{
+				Position: Point{X: cell.X, Y: cell.Y},
+				Layer:    cell.Layer,
+			})
+
+		case "Crate":
+			var crate EntityCrate
+			if err := json.Unmarshal(mapped, &crate); err != nil {
+				return nil, err
+			}
+			level.Entities = append(level.Entities, &Crate{
+				Position: Point{X: crate.X, Y: crate.Y},
+				Layer:    crate.Layer,
+			})
+
 		case "Snake":
 			var snake EntitySnake
 			if err := json.Unmarshal(mapped, &snake); err != nil {
@@ -249,10 +454,34 @@ func DeserializeLevel(data []byte) (*Level, error) {
 				Segments:       points,
 				Layer:          layer,
 				GrowOnNextMove: snake.GrowOnNextMove,
+				FusedSnakeIDs:  snake.FusedSnakeIDs,
 			})
+
+		default:
+			diagnostics = append(diagnostics, Diagnostic{EntityIndex: i, Message: fmt.Sprintf("unknown entity type %q", typ)})
 		}
 	}
 
+	if len(diagnostics) > 0 {
+		return nil, &ValidationError{Diagnostics: diagnostics}
+	}
 	level.Grid = grid
 	return level, nil
 }
+
+// validFusedSnakeIDs filters out IDs of snakes that no longer exist, like toJSON() in snake.ts
+func validFusedSnakeIDs(snake *Snake, level *Level) []string {
+	var ids []string
+	for _, id := range snake.FusedSnakeIDs {
+		if id == snake.ID {
+			continue
+		}
+		for _, other := range getSnakes(level) {
+			if other.ID == id {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids
+}
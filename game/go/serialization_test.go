@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDeserializeLevelFormatVersionTooNew(t *testing.T) {
+	_, err := LoadLevel("levels/tests/format-version-too-new.json")
+	if !errors.Is(err, ErrFormatVersionTooNew) {
+		t.Errorf("Expected ErrFormatVersionTooNew, but got %v", err)
+	}
+}
+
+func TestDeserializeLevelInvalidFormat(t *testing.T) {
+	_, err := DeserializeLevel([]byte(`{"format": "not-snakeshift", "formatVersion": 6, "entities": [], "entityTypes": []}`))
+	if !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Expected ErrInvalidFormat, but got %v", err)
+	}
+	_, err = DeserializeLevel([]byte(`{"format": "snakeshift", "formatVersion": 0, "entities": [], "entityTypes": []}`))
+	if !errors.Is(err, ErrInvalidFormatVersion) {
+		t.Errorf("Expected ErrInvalidFormatVersion, but got %v", err)
+	}
+}
+
+func TestDeserializeLevelUpgradesOldFormatVersions(t *testing.T) {
+	// Version 1: snake segments have a "size" instead of "width" and "height",
+	// and there's no levelInfo (implied 16x16 before version 3).
+	// Food is called "Collectable" and has junk "_time" and "solid" properties.
+	level, err := DeserializeLevel([]byte(`{
+		"format": "snakeshift",
+		"formatVersion": 1,
+		"entities": [
+			{"x": 3, "y": 4, "width": 1, "height": 1, "layer": 1, "_time": 1234, "solid": false},
+			{"id": "a", "segments": [{"x": 1, "y": 1, "size": 1, "layer": 1}, {"x": 2, "y": 1, "size": 1, "layer": 1}], "growOnNextMove": false}
+		],
+		"entityTypes": ["Collectable", "Snake"],
+		"activePlayerEntityIndex": 1
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to deserialize level: %v", err)
+	}
+	if level.Info.Width != 16 || level.Info.Height != 16 {
+		t.Errorf("Expected historical default size 16x16, but got %dx%d", level.Info.Width, level.Info.Height)
+	}
+	if len(level.Entities) != 2 {
+		t.Fatalf("Expected 2 entities, but got %d", len(level.Entities))
+	}
+	food, ok := level.Entities[0].(*Food)
+	if !ok {
+		t.Fatalf("Expected \"Collectable\" to be loaded as *Food, but got %T", level.Entities[0])
+	}
+	if food.Position != (Point{X: 3, Y: 4}) || food.Layer != White {
+		t.Errorf("Food loaded incorrectly: %+v", food)
+	}
+	snake, ok := level.Entities[1].(*Snake)
+	if !ok {
+		t.Fatalf("Expected *Snake, but got %T", level.Entities[1])
+	}
+	if len(snake.Segments) != 2 || snake.Layer != White {
+		t.Errorf("Snake loaded incorrectly: %+v", snake)
+	}
+}
+
+func TestFusedSnakeIDsRoundTrip(t *testing.T) {
+	level, err := LoadLevel("levels/sketches/fused-snakes.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	snake := getSnakeByID("01856ded-f545-42cb-b411-68112b568a53", level)
+	if len(snake.FusedSnakeIDs) != 2 {
+		t.Fatalf("Expected 2 fused snake IDs, but got %v", snake.FusedSnakeIDs)
+	}
+	// IDs of snakes that don't exist are dropped when saving.
+	snake.FusedSnakeIDs = append(snake.FusedSnakeIDs, "nonexistent")
+	data, err := SerializeLevel(level)
+	if err != nil {
+		t.Fatalf("Failed to serialize level: %v", err)
+	}
+	reloaded, err := DeserializeLevel(data)
+	if err != nil {
+		t.Fatalf("Failed to deserialize level: %v", err)
+	}
+	snake.FusedSnakeIDs = snake.FusedSnakeIDs[:2]
+	if !Equal(level, reloaded) {
+		t.Errorf("Level changed after round trip")
+	}
+	if strings.Count(string(data), "fusedSnakeIds") != 5 {
+		t.Errorf("Expected fusedSnakeIds only on the 5 fused snakes")
+	}
+}
@@ -1,145 +1,344 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math"
 	"slices"
+	"time"
 )
 
-const maxSubsequenceLength = 12 // Maximum length of subsequences to consider for simplification.
+var ErrInvalidPlaythrough = errors.New("invalid playthrough")
 
-type SubSequencePatch struct {
-	moveInputs  []MoveInput
-	deleteCount int // Number of moves to delete from the original playthrough.
+const maxSubsequenceLength = 12 // Default maximum length of subsequences to consider for simplification.
+
+type SimplifyOptions struct {
+	MaxDepth    int           // maximum length of replacement subsequences, or 0 for maxSubsequenceLength
+	MaxStates   int           // maximum number of states to visit when searching for a shortcut from each state, or 0 for no limit
+	MaxDuration time.Duration // time budget for the whole simplification, or 0 for no limit
+}
+
+type SimplifyReport struct {
+	MoveInputs           []MoveInput // the simplified playthrough
+	OriginalMoves        int
+	CycleMovesRemoved    int // moves removed by cutting out loops back to an earlier state
+	ShortcutMovesRemoved int // moves removed by replacing subsequences with shorter ones
+	Shortcuts            int // number of subsequences replaced
+	SnakeSwitches        int // snake switches left after grouping moves by snake
+	Passes               int // passes over the playthrough, including the last one that found nothing to simplify
+	StatesVisited        int
+	// Complete is false if the time budget ran out before reaching a fixed point,
+	// in which case the playthrough is still valid, but may be simplified further.
+	Complete bool
+}
+
+// SimplifyPlaythrough shortens a playthrough, keeping it valid.
+// The simplified playthrough ends in the same state, or wins the level if a shortcut to winning is found.
+//
+// It may be too expensive to find an optimal playthrough from scratch,
+// but assuming we have a valid playthrough, we can search breadth-first from each state,
+// up to a limited depth, for any later state in the playthrough (or the winning of the level).
+// If one is found in fewer moves than the original subsequence, the subsequence is replaced.
+// Loops back to an earlier state are cut out first, and the process is repeated until nothing changes.
+// This will not guarantee an optimal playthrough, but it will be at least as short as the original.
+//
+// If the context is cancelled, it returns the report so far along with the context's error.
+func SimplifyPlaythrough(ctx context.Context, moveInputs []MoveInput, level *Level, opts SimplifyOptions) (SimplifyReport, error) {
+	report := SimplifyReport{
+		MoveInputs:    slices.Clone(moveInputs),
+		OriginalMoves: len(moveInputs),
+	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = maxSubsequenceLength
+	}
+	searchCtx := ctx
+	if opts.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		searchCtx, cancel = context.WithTimeout(ctx, opts.MaxDuration)
+		defer cancel()
+	}
+
+	states, err := playthroughStates(report.MoveInputs, level)
+	if err != nil {
+		return report, err
+	}
+
+	for {
+		report.Passes++
+		changed := false
+
+		// Cut out loops, where the playthrough returns to an earlier state.
+		// Keeping the first index each state is seen at, when a state is seen again,
+		// everything after its first occurrence is discarded.
+		kept := []*Level{}
+		keptMoves := []MoveInput{}
+		seen := map[string]int{}
+		for i, state := range states {
+			key := string(CanonicalBytes(state))
+			if index, ok := seen[key]; ok {
+				for _, discarded := range kept[index+1:] {
+					delete(seen, string(CanonicalBytes(discarded)))
+				}
+				kept = kept[:index+1]
+				keptMoves = keptMoves[:index]
+				changed = true
+				continue
+			}
+			seen[key] = len(kept)
+			kept = append(kept, state)
+			if i > 0 {
+				keptMoves = append(keptMoves, report.MoveInputs[i-1])
+			}
+		}
+		report.CycleMovesRemoved += len(report.MoveInputs) - len(keptMoves)
+		states, report.MoveInputs = kept, keptMoves
+
+		// Replace subsequences with shorter ones that lead to the same state, or win.
+		for i := 0; i < len(report.MoveInputs); i++ {
+			shortcut, deleteCount, visited := findShortcut(searchCtx, states, i, opts)
+			report.StatesVisited += visited
+			if searchCtx.Err() != nil {
+				break
+			}
+			if shortcut == nil {
+				continue
+			}
+			shortcutStates, err := playthroughStates(shortcut, states[i])
+			if err != nil {
+				return report, err // shouldn't happen, since the search only takes valid moves
+			}
+			report.MoveInputs = slices.Concat(report.MoveInputs[:i], shortcut, report.MoveInputs[i+deleteCount:])
+			states = slices.Concat(states[:i], shortcutStates, states[i+deleteCount+1:])
+			report.ShortcutMovesRemoved += deleteCount - len(shortcut)
+			report.Shortcuts++
+			changed = true
+			// Continue on from the end of the shortcut, since a shorter path to here would have been found already.
+			i += len(shortcut) - 1
+		}
+
+		if err := ctx.Err(); err != nil {
+			return report, fmt.Errorf("simplification cancelled after %d passes: %w", report.Passes, err)
+		}
+		if searchCtx.Err() != nil || !changed {
+			report.Complete = searchCtx.Err() == nil
+			break
+		}
+	}
+
+	report.MoveInputs = groupSnakeMoves(report.MoveInputs, level)
+	report.SnakeSwitches = countSnakeSwitches(report.MoveInputs)
+	return report, nil
 }
 
+// simplifyPlaythrough simplifies a playthrough with the default options and no time limit.
+// It panics if the playthrough is invalid.
 func simplifyPlaythrough(moveInputs []MoveInput, level *Level) []MoveInput {
-	// Simplify the playthrough by generating new subsequences to patch into the playthrough.
-	// It may be too expensive to find an optimal playthrough from scratch,
-	// trying every move in every state (this explodes combinatorially),
-	// but assuming we have a valid playthrough,
-	// we can try every combination of N moves at each step, and see if it
-	// results in any later state in the playthrough (or the winning of the level).
-	// If it does, and it's shorter than the original subsequence,
-	// we can replace the original subsequence with the shorter one.
-	// - If we ever generate a state that exists EARLIER in the playthrough,
-	//   we can skip that tree of exploration, since it will be a redundant loop.
-	//   Whether that helps performance overall is unclear.
-	//   It probably would if we use hashes, not sure though if we're comparing every field individually.
-	//   Probably still would but not as much.
-	// - We could apply this process recursively, until no simplification is made.
-	// - This function will not guarantee an optimal playthrough,
-	//   but it will guarantee a valid playthrough that is at least as short as the original.
-
-	fmt.Printf("Trying to simplify playthrough with %d moves (%v)...\n", len(moveInputs), String(moveInputs))
-
-	// First, create a list of states that the playthrough goes through.
+	report, err := SimplifyPlaythrough(context.Background(), moveInputs, level, SimplifyOptions{})
+	if err != nil {
+		panic(err)
+	}
+	return report.MoveInputs
+}
+
+// playthroughStates returns the states a playthrough goes through, including the initial state.
+func playthroughStates(moveInputs []MoveInput, level *Level) ([]*Level, error) {
 	states := make([]*Level, 0, len(moveInputs)+1)
 	states = append(states, copyLevel(level))
 	for i, input := range moveInputs {
-		lastState := states[len(states)-1]
-		newState := copyLevel(lastState)
-		move := AnalyzeMoveRelative(
-			getSnakeByID(input.SnakeID, newState),
-			input.Direction.X,
-			input.Direction.Y,
-			newState,
-		)
-		if !move.Valid {
-			panic("Invalid move input at index " + fmt.Sprint(i) + ": snake ID '" + input.SnakeID + "', direction (" + fmt.Sprint(input.Direction.X) + ", " + fmt.Sprint(input.Direction.Y) + ")")
+		state, ok := applyMoveInputs(states[i], input)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid move at index %d: snake ID '%s', direction (%d, %d)", ErrInvalidPlaythrough, i, input.SnakeID, input.Direction.X, input.Direction.Y)
 		}
-		TakeMove(move, newState)
-		states = append(states, newState)
-	}
-
-	// Detect any redundant state cycles in the playthrough.
-	for i := 0; i < len(states); i++ {
-		for j := i + 1; j < len(states); j++ {
-			// fmt.Printf("States %d and %d: %v\n", i, j, Equal(states[i], states[j]))
-			if Equal(states[i], states[j]) {
-				// Detected a cycle: states[i] returns to the same state as states[j].
-				// Remove the cycle by skipping states[i:j] and related moves.
-				// I added tests for this, but I still find this suspicious.
-				// Shouldn't this be an off-by-one error?
-				states = append(states[:i], states[j:]...)
-				moveInputs = append(moveInputs[:i], moveInputs[j:]...)
-				i-- // Adjust i to account for the removed elements.
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// findShortcut searches breadth-first from states[start] for the shortcut that saves the most moves,
+// returning the moves to replace and how many moves of the playthrough they replace,
+// or nil if there's no shorter route within the limits.
+func findShortcut(ctx context.Context, states []*Level, start int, opts SimplifyOptions) ([]MoveInput, int, int) {
+	moves := len(states) - 1
+	laterStates := make(map[string]int, moves-start)
+	for j := moves; j > start; j-- {
+		laterStates[string(CanonicalBytes(states[j]))] = j
+	}
+	// States up to and including the start would only make loops.
+	visited := make(map[string]bool, start+1)
+	for _, state := range states[:start+1] {
+		visited[string(CanonicalBytes(state))] = true
+	}
+	initialVisited := len(visited)
+
+	nodes := []solverNode{{parent: -1}}
+	queue := []*Level{states[start]}
+	queueNodes := []int{0}
+	bestNode, bestDeleteCount, bestSaved := -1, 0, 0
+
+	for len(queue) > 0 {
+		current := queue[0]
+		currentIndex := queueNodes[0]
+		queue = queue[1:]
+		queueNodes = queueNodes[1:]
+		depth := nodes[currentIndex].depth
+		// Moves can't be saved by anything at least as long as the rest of the playthrough.
+		if depth >= opts.MaxDepth || moves-start-(depth+1) <= bestSaved {
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		for _, successor := range successors(current) {
+			key := string(CanonicalBytes(successor.level))
+			if visited[key] {
+				continue
+			}
+			if opts.MaxStates > 0 && len(visited)-initialVisited >= opts.MaxStates {
+				queue = nil
 				break
 			}
+			visited[key] = true
+			nodes = append(nodes, solverNode{parent: currentIndex, moveInput: successor.moveInput, depth: depth + 1})
+
+			deleteCount := 0
+			if levelIsWon(successor.level) {
+				// When it comes to actually winning, the fastest route is the best route.
+				deleteCount = moves - start
+			} else if j, ok := laterStates[key]; ok {
+				deleteCount = j - start
+			} else {
+				queue = append(queue, successor.level)
+				queueNodes = append(queueNodes, len(nodes)-1)
+				continue
+			}
+			if saved := deleteCount - (depth + 1); saved > bestSaved {
+				bestNode, bestDeleteCount, bestSaved = len(nodes)-1, deleteCount, saved
+			}
 		}
 	}
-	fmt.Printf("After any redundant cycles removed: %d moves (%v)\n", len(moveInputs), String(moveInputs))
-
-	// Try to replace subsequences of moves with shorter ones that lead to the same state.
-	for i := 0; i < len(moveInputs); i++ {
-		// 4^maxSubsequenceLength is the maximum number of subsequences to consider.
-		// The likely length is several orders of magnitude smaller than that.
-		// For a snake longer than one segment, you can't move backwards,
-		// so there are USUALLY no more than 3 valid directions at a given step.
-		possiblePatches := make([]SubSequencePatch, 0, int(math.Pow(3, float64(maxSubsequenceLength))))
-		visitPuzzleStates(level, func(l *Level, newSubsequence []MoveInput) bool {
-			// Check if the level is won OR matches a later state in the playthrough.
-			if levelIsWon(l) {
-				possiblePatches = append(possiblePatches, SubSequencePatch{
-					moveInputs:  newSubsequence,
-					deleteCount: len(moveInputs) - i,
-				})
-				// When it comes to actually winning, the fastest route is the best route.
-				// (though there may be multiple routes of equal length)
-				// If we find a winning state, there's no need to keep searching.
-				return true
+
+	visitedCount := len(visited) - initialVisited
+	if bestNode == -1 {
+		return nil, 0, visitedCount
+	}
+	return solutionTo(nodes, bestNode), bestDeleteCount, visitedCount
+}
+
+// countSnakeSwitches counts the times consecutive moves are of different snakes.
+func countSnakeSwitches(moveInputs []MoveInput) int {
+	switches := 0
+	for i := 1; i < len(moveInputs); i++ {
+		if moveInputs[i].SnakeID != moveInputs[i-1].SnakeID {
+			switches++
+		}
+	}
+	return switches
+}
+
+// groupSnakeMoves reorders commuting moves of different snakes to reduce the number of snake switches.
+// A move is moved past a run of other snakes' moves, to join the nearest earlier or later move of the same snake,
+// if every move stays valid, the level isn't won any sooner, and the state after the run is the same,
+// so the final state is identical.
+// It assumes the moves are valid, returning them unchanged otherwise.
+func groupSnakeMoves(moveInputs []MoveInput, level *Level) []MoveInput {
+	moveInputs = slices.Clone(moveInputs)
+	states := []*Level{copyLevel(level)}
+	for _, input := range moveInputs {
+		state, ok := applyMoveInputs(states[len(states)-1], input)
+		if !ok {
+			return moveInputs
+		}
+		states = append(states, state)
+	}
+
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(moveInputs); i++ {
+			snakeID := moveInputs[i].SnakeID
+			// Find where the move could go: just after the previous move of the same snake,
+			// or just before the next one.
+			targets := []int{}
+			for j := i - 1; j >= 0; j-- {
+				if moveInputs[j].SnakeID == snakeID {
+					if j+1 < i {
+						targets = append(targets, j+1)
+					}
+					break
+				}
 			}
-			for j := i + 1; j < len(states); j++ {
-				if Equal(l, states[j]) {
-					// Possible optimization: don't append unless the new subsequence is shorter than the original.
-					// Could make a function for that, and could make one for calculating the length savings.
-					possiblePatches = append(possiblePatches, SubSequencePatch{
-						moveInputs:  newSubsequence,
-						deleteCount: j - i, // TODO: vet for possible off-by-one error
-					})
-					// return true
-					return false // We found a match, but we want to keep searching for better subsequences.
+			for j := i + 1; j < len(moveInputs); j++ {
+				if moveInputs[j].SnakeID == snakeID {
+					if j-1 > i {
+						targets = append(targets, j-1)
+					}
+					break
 				}
 			}
-			return false
-		}, maxSubsequenceLength)
-		fmt.Println("Starting at index", i, "possible subsequence patches:", len(possiblePatches))
-		if len(possiblePatches) > 0 {
-			// Sort patches by length savings
-			slices.SortStableFunc(possiblePatches, func(a, b SubSequencePatch) int {
-				return (b.deleteCount - len(b.moveInputs)) - (a.deleteCount - len(a.moveInputs))
-			})
-			// Apply the best patch if it saves any moves.
-			bestPatch := possiblePatches[0]
-			saved := bestPatch.deleteCount - len(bestPatch.moveInputs)
-			fmt.Println("Best patch:", String(bestPatch.moveInputs), "saves", saved, "moves")
-			if saved > 0 {
-				// Replace the subsequence starting at i with the new subsequence.
-				// TODO: vet this for off-by-one errors, etc.
-				moveInputs = append(moveInputs[:i], append(bestPatch.moveInputs, moveInputs[i+bestPatch.deleteCount:]...)...)
-				states = append(states[:i+1], states[i+bestPatch.deleteCount:]...)
-				// Continue on to the next index as normal.
+			for _, target := range targets {
+				reordered := slices.Insert(slices.Delete(slices.Clone(moveInputs), i, i+1), target, moveInputs[i])
+				if countSnakeSwitches(reordered) >= countSnakeSwitches(moveInputs) {
+					continue
+				}
+				start, end := min(i, target), max(i, target)+1
+				windowStates := []*Level{states[start]}
+				valid := true
+				for k, input := range reordered[start:end] {
+					state, ok := applyMoveInputs(windowStates[len(windowStates)-1], input)
+					// Winning ends the level, so the level can't be won before the last move in the window.
+					if !ok || (levelIsWon(state) && start+k+1 < end) {
+						valid = false
+						break
+					}
+					windowStates = append(windowStates, state)
+				}
+				if !valid || !Equal(windowStates[len(windowStates)-1], states[end]) {
+					continue
+				}
+				moveInputs = reordered
+				copy(states[start:end+1], windowStates)
+				improved = true
+				break
 			}
 		}
 	}
-
 	return moveInputs
-
 }
 
-func visitPuzzleStates(level *Level, handleState func(*Level, []MoveInput) bool, depth int, moveInputs ...MoveInput) {
-	// Use BFS to visit all states up to a certain depth.
-	possibleMoves := getAllPossibleMoves(level)
-	for _, move := range possibleMoves {
-		newLevel := copyLevel(level)
-		TakeMove(move, newLevel)
-		newMoveInputs := append(moveInputs, MoveToMoveInput(move))
-		if handleState(newLevel, newMoveInputs) {
-			// Allow aborting the search if a [partial] solution is found.
-			return
-		}
-		if depth > 0 {
-			visitPuzzleStates(newLevel, handleState, depth-1, newMoveInputs...)
+// applyMoveInputs returns the state after the given moves, leaving the level unchanged,
+// or false if any move is invalid.
+func applyMoveInputs(level *Level, moveInputs ...MoveInput) (*Level, bool) {
+	level = copyLevel(level)
+	for _, input := range moveInputs {
+		move, ok := analyzeMoveInput(input, level)
+		if !ok {
+			return nil, false
 		}
+		TakeMove(move, level)
+	}
+	return level, true
+}
+
+// SimplifyPlaythroughFile simplifies a playthrough of a level, with IDs relative to the public folder like LoadLevel,
+// returning the simplified playthrough in the same format as the web version of the game saves.
+// The moves are taken from the playthrough, but played from the level, so the level can be edited after recording.
+func SimplifyPlaythroughFile(ctx context.Context, levelId, playthroughId string, opts SimplifyOptions) ([]byte, SimplifyReport, error) {
+	level, err := LoadLevel(levelId)
+	if err != nil {
+		return nil, SimplifyReport{}, err
+	}
+	_, moveInputs, err := LoadPlaythrough(playthroughId)
+	if err != nil {
+		return nil, SimplifyReport{}, err
+	}
+	report, err := SimplifyPlaythrough(ctx, moveInputs, level, opts)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return nil, report, fmt.Errorf("failed to simplify playthrough %s: %w", playthroughId, err)
+	}
+	// Even if cancelled, the playthrough so far is valid.
+	serialized, serializeErr := SerializePlaythrough(level, report.MoveInputs)
+	if serializeErr != nil {
+		return nil, report, fmt.Errorf("failed to serialize playthrough: %w", serializeErr)
 	}
+	return serialized, report, err
 }
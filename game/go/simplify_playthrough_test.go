@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -162,3 +164,177 @@ func TestSimplifyPlaythroughByFindingNewRoutes(t *testing.T) {
 		t.Errorf("Playthrough didn't match.\nExpected:\n  %v\nActual:\n  %v\nOriginal:\n  %v", String(expected), String(actual), String(moveInputs))
 	}
 }
+
+func TestGroupSnakeMoves(t *testing.T) {
+	level := &Level{
+		Info: LevelInfo{Width: 5, Height: 3},
+		Grid: [][]CollisionLayer{{Black, Black, Black, Black, Black}, {Black, Black, Black, Black, Black}, {Black, Black, Black, Black, Black}},
+		Entities: []Entity{
+			&Snake{ID: "a", Layer: White, Segments: []Point{{X: 1, Y: 0}, {X: 0, Y: 0}}},
+			&Snake{ID: "b", Layer: White, Segments: []Point{{X: 1, Y: 2}, {X: 0, Y: 2}}},
+			&Food{Position: Point{X: 4, Y: 2}, Layer: White},
+		},
+	}
+	moveInputs := []MoveInput{
+		{Direction: Right, SnakeID: "a"},
+		{Direction: Right, SnakeID: "b"},
+		{Direction: Right, SnakeID: "a"},
+		{Direction: Up, SnakeID: "b"},
+		{Direction: Right, SnakeID: "a"},
+	}
+
+	actual := groupSnakeMoves(moveInputs, level)
+
+	expected := []MoveInput{
+		{Direction: Right, SnakeID: "b"},
+		{Direction: Up, SnakeID: "b"},
+		{Direction: Right, SnakeID: "a"},
+		{Direction: Right, SnakeID: "a"},
+		{Direction: Right, SnakeID: "a"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but got %v", String(expected), String(actual))
+	}
+	before, _ := applyMoveInputs(level, moveInputs...)
+	after, _ := applyMoveInputs(level, actual...)
+	if !Equal(before, after) {
+		t.Errorf("Expected the same final state after reordering")
+	}
+}
+
+func TestGroupSnakeMovesKeepsDependentOrder(t *testing.T) {
+	// Snake a can only move up after snake b has moved out of the way.
+	level := &Level{
+		Info: LevelInfo{Width: 3, Height: 2},
+		Grid: [][]CollisionLayer{{Black, Black, Black}, {Black, Black, Black}},
+		Entities: []Entity{
+			&Snake{ID: "a", Layer: White, Segments: []Point{{X: 1, Y: 0}, {X: 2, Y: 0}}},
+			&Snake{ID: "b", Layer: White, Segments: []Point{{X: 0, Y: 0}}},
+			&Food{Position: Point{X: 2, Y: 1}, Layer: White},
+		},
+	}
+	moveInputs := []MoveInput{
+		{Direction: Down, SnakeID: "a"},
+		{Direction: Left, SnakeID: "a"},
+		{Direction: Right, SnakeID: "b"},
+		{Direction: Up, SnakeID: "a"},
+	}
+
+	actual := groupSnakeMoves(moveInputs, level)
+
+	if !reflect.DeepEqual(actual, moveInputs) {
+		t.Errorf("Expected moves to be unchanged, but got %v", String(actual))
+	}
+}
+
+func TestGroupSnakeMovesKeepsWinningMoveLast(t *testing.T) {
+	// Snake b's move commutes with snake a's last move, but snake a's last move wins the level,
+	// so moving it earlier would leave snake b's move after the level is won.
+	level := &Level{
+		Info: LevelInfo{Width: 4, Height: 2},
+		Grid: [][]CollisionLayer{{Black, Black, Black, Black}, {Black, Black, Black, Black}},
+		Entities: []Entity{
+			&Snake{ID: "a", Layer: White, Segments: []Point{{X: 1, Y: 0}}},
+			&Snake{ID: "b", Layer: White, Segments: []Point{{X: 1, Y: 1}}},
+			&Food{Position: Point{X: 3, Y: 0}, Layer: White},
+		},
+	}
+	moveInputs := []MoveInput{
+		{Direction: Right, SnakeID: "a"},
+		{Direction: Up, SnakeID: "b"},
+		{Direction: Right, SnakeID: "a"},
+	}
+
+	actual := groupSnakeMoves(moveInputs, level)
+
+	if !reflect.DeepEqual(actual, moveInputs) {
+		t.Errorf("Expected moves to be unchanged, but got %v", String(actual))
+	}
+}
+
+func TestSimplifyPlaythroughReport(t *testing.T) {
+	level, err := LoadLevel("levels/tests/move-right-5x-to-win.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	snakeId := "08ef6a5d-f983-4079-ae94-ea6cafd136f2"
+	moveInputs := []MoveInput{
+		{Direction: Up, SnakeID: snakeId},
+		{Direction: Up, SnakeID: snakeId}, // invalid, since it's out of bounds
+	}
+	_, err = SimplifyPlaythrough(context.Background(), moveInputs, level, SimplifyOptions{})
+	if !errors.Is(err, ErrInvalidPlaythrough) {
+		t.Errorf("Expected ErrInvalidPlaythrough, but got %v", err)
+	}
+
+	// Loop back to the starting state, then go right the long way around.
+	moveInputs = []MoveInput{
+		{Direction: Right, SnakeID: snakeId},
+		{Direction: Up, SnakeID: snakeId},
+		{Direction: Left, SnakeID: snakeId},
+		{Direction: Down, SnakeID: snakeId},
+		{Direction: Right, SnakeID: snakeId},
+		{Direction: Up, SnakeID: snakeId},
+		{Direction: Right, SnakeID: snakeId},
+		{Direction: Down, SnakeID: snakeId},
+	}
+	report, err := SimplifyPlaythrough(context.Background(), moveInputs, level, SimplifyOptions{})
+	if err != nil {
+		t.Fatalf("Failed to simplify playthrough: %v", err)
+	}
+	expected := []MoveInput{
+		{Direction: Right, SnakeID: snakeId},
+		{Direction: Right, SnakeID: snakeId},
+	}
+	if !reflect.DeepEqual(report.MoveInputs, expected) {
+		t.Errorf("Expected %v, but got %v", String(expected), String(report.MoveInputs))
+	}
+	if report.OriginalMoves != 8 || report.CycleMovesRemoved != 4 || report.ShortcutMovesRemoved != 2 || report.Shortcuts != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if !report.Complete || report.Passes != 2 {
+		t.Errorf("Expected to reach a fixed point on the second pass, but got %+v", report)
+	}
+}
+
+func TestSimplifyPlaythroughCancelled(t *testing.T) {
+	level, err := LoadLevel("levels/tests/move-right-to-win.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	snakeId := "04f13bbb-2635-4470-9849-eaaecc079201"
+	moveInputs := []MoveInput{
+		{Direction: Up, SnakeID: snakeId},
+		{Direction: Right, SnakeID: snakeId},
+		{Direction: Down, SnakeID: snakeId},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := SimplifyPlaythrough(ctx, moveInputs, level, SimplifyOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, but got %v", err)
+	}
+	if report.Complete || len(report.MoveInputs) != len(moveInputs) {
+		t.Errorf("Expected the playthrough to be left as it was, but got %+v", report)
+	}
+}
+
+func TestSimplifyPlaythroughFile(t *testing.T) {
+	serialized, report, err := SimplifyPlaythroughFile(context.Background(), "levels/easy/001-movement.json", "levels/easy/001-movement-playthrough.json", SimplifyOptions{})
+	if err != nil {
+		t.Fatalf("Failed to simplify playthrough: %v", err)
+	}
+	if report.OriginalMoves != 22 || len(report.MoveInputs) != 22 {
+		t.Errorf("Expected the optimal playthrough to stay at 22 moves, but got %d from %d", len(report.MoveInputs), report.OriginalMoves)
+	}
+	states, moveInputs, err := DeserializePlaythrough(serialized)
+	if err != nil {
+		t.Fatalf("Failed to deserialize simplified playthrough: %v", err)
+	}
+	if !reflect.DeepEqual(moveInputs, report.MoveInputs) {
+		t.Errorf("Expected %v, but got %v", String(report.MoveInputs), String(moveInputs))
+	}
+	if !levelIsWon(states[len(states)-1]) {
+		t.Errorf("Expected simplified playthrough to win")
+	}
+}
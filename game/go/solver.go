@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnsolvable         = errors.New("level is unsolvable")
+	ErrSearchLimitReached = errors.New("search limit reached")
+)
+
+type SolveOptions struct {
+	MaxDepth  int // maximum solution length to consider, or 0 for no limit
+	MaxStates int // maximum number of distinct states to visit, or 0 for no limit
+}
+
+// solverNode records how a state was reached, so the solution can be reconstructed
+// without keeping every level in memory.
+type solverNode struct {
+	parent    int
+	moveInput MoveInput
+	depth     int
+}
+
+func solutionTo(nodes []solverNode, index int) []MoveInput {
+	moveInputs := make([]MoveInput, nodes[index].depth)
+	for ; nodes[index].parent >= 0; index = nodes[index].parent {
+		moveInputs[nodes[index].depth-1] = nodes[index].moveInput
+	}
+	return moveInputs
+}
+
+type successor struct {
+	level     *Level
+	moveInput MoveInput
+}
+
+// successors returns the states reachable in one move, leaving the given level unchanged.
+func successors(level *Level) []successor {
+	moves := getAllPossibleMoves(level)
+	result := make([]successor, len(moves))
+	for i, move := range moves {
+		newLevel := copyLevel(level)
+		// The move refers to entities in the original level, so it needs to be analyzed again for the copy.
+		moveInput := MoveToMoveInput(move)
+		TakeMove(AnalyzeMoveRelative(getSnakeByID(moveInput.SnakeID, newLevel), moveInput.Direction.X, moveInput.Direction.Y, newLevel), newLevel)
+		result[i] = successor{level: newLevel, moveInput: moveInput}
+	}
+	return result
+}
+
+// Solve does a breadth-first search over all reachable states, returning a shortest solution.
+// If the whole state space is exhausted without finding a solution, it returns ErrUnsolvable.
+// If a limit is reached first, it returns ErrSearchLimitReached, meaning there's no solution within the limits.
+func Solve(level *Level, opts SolveOptions) ([]MoveInput, error) {
+	if levelIsWon(level) {
+		return []MoveInput{}, nil
+	}
+	if dead, reasons := IsDeadState(level); dead {
+		return nil, deadStateError(reasons)
+	}
+
+	nodes := []solverNode{{parent: -1}}
+	queue := []*Level{copyLevel(level)}
+	queueNodes := []int{0}
+	visited := map[string]bool{string(CanonicalBytes(level)): true}
+	limited := false
+
+	for len(queue) > 0 {
+		current := queue[0]
+		currentIndex := queueNodes[0]
+		queue = queue[1:]
+		queueNodes = queueNodes[1:]
+		depth := nodes[currentIndex].depth
+		if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+			limited = true
+			continue
+		}
+
+		for _, successor := range successors(current) {
+			newLevel, moveInput := successor.level, successor.moveInput
+			key := string(CanonicalBytes(newLevel))
+			if visited[key] {
+				continue
+			}
+			if opts.MaxStates > 0 && len(visited) >= opts.MaxStates {
+				return nil, fmt.Errorf("%w: visited %d states without finding a solution", ErrSearchLimitReached, len(visited))
+			}
+			visited[key] = true
+			nodes = append(nodes, solverNode{parent: currentIndex, moveInput: moveInput, depth: depth + 1})
+
+			if levelIsWon(newLevel) {
+				return solutionTo(nodes, len(nodes)-1), nil
+			}
+			if isDeadState(newLevel) {
+				continue
+			}
+			queue = append(queue, newLevel)
+			queueNodes = append(queueNodes, len(nodes)-1)
+		}
+	}
+
+	if limited {
+		return nil, fmt.Errorf("%w: no solution within %d moves (visited %d states)", ErrSearchLimitReached, opts.MaxDepth, len(visited))
+	}
+	return nil, fmt.Errorf("%w: exhausted all %d reachable states", ErrUnsolvable, len(visited))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSolveFindsShortestSolution(t *testing.T) {
+	level, err := LoadLevel("levels/easy/001-movement.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	solution, err := Solve(level, SolveOptions{})
+	if err != nil {
+		t.Fatalf("Failed to solve level: %v", err)
+	}
+	if len(solution) != 22 {
+		t.Errorf("Expected a 22 move solution, but got %d moves: %s", len(solution), String(solution))
+	}
+	for i, input := range solution {
+		move := AnalyzeMoveRelative(getSnakeByID(input.SnakeID, level), input.Direction.X, input.Direction.Y, level)
+		if !move.Valid {
+			t.Fatalf("Move %d of solution is invalid", i)
+		}
+		TakeMove(move, level)
+	}
+	if !levelIsWon(level) {
+		t.Errorf("Expected solution to win the level")
+	}
+}
+
+func TestSolveUnsolvable(t *testing.T) {
+	// A white snake can't eat black food.
+	level := &Level{
+		Info:     LevelInfo{Width: 3, Height: 1},
+		Grid:     [][]CollisionLayer{{Black, Black, Black}},
+		Entities: []Entity{&Snake{ID: "a", Layer: White, Segments: []Point{{X: 0, Y: 0}}}, &Food{Position: Point{X: 2, Y: 0}, Layer: Black}},
+	}
+	_, err := Solve(level, SolveOptions{})
+	if !errors.Is(err, ErrUnsolvable) {
+		t.Errorf("Expected ErrUnsolvable, but got %v", err)
+	}
+}
+
+func TestSolveWithinLimits(t *testing.T) {
+	level, err := LoadLevel("levels/easy/001-movement.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	_, err = Solve(level, SolveOptions{MaxDepth: 5})
+	if !errors.Is(err, ErrSearchLimitReached) {
+		t.Errorf("Expected ErrSearchLimitReached with MaxDepth, but got %v", err)
+	}
+	_, err = Solve(level, SolveOptions{MaxStates: 10})
+	if !errors.Is(err, ErrSearchLimitReached) {
+		t.Errorf("Expected ErrSearchLimitReached with MaxStates, but got %v", err)
+	}
+}
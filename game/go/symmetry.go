@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// A SymmetryOp transforms a level into an equivalent one: the same puzzle, rotated, reflected, and/or inverted.
+// The first eight are the symmetries of a rectangle (well, a square, as rotating by 90 degrees swaps the dimensions),
+// and SwapLayers can be combined with any of them, as in Rotate90|SwapLayers.
+type SymmetryOp int
+
+const (
+	Identity         SymmetryOp = iota
+	Rotate90                    // clockwise
+	Rotate180                   //
+	Rotate270                   // clockwise, i.e. 90 degrees counterclockwise
+	FlipHorizontal              // mirror left to right
+	FlipVertical                // mirror top to bottom
+	FlipDiagonal                // transpose, mirroring across the diagonal from the top left
+	FlipAntiDiagonal            // mirror across the diagonal from the top right
+	// SwapLayers swaps black and white, like an inverter does for snakes.
+	SwapLayers SymmetryOp = 8
+)
+
+// SymmetryOps lists every distinct transformation, starting with Identity.
+var SymmetryOps = func() []SymmetryOp {
+	var ops []SymmetryOp
+	for _, swap := range []SymmetryOp{0, SwapLayers} {
+		for op := Identity; op <= FlipAntiDiagonal; op++ {
+			ops = append(ops, op|swap)
+		}
+	}
+	return ops
+}()
+
+var symmetryOpNames = []string{"identity", "rotate90", "rotate180", "rotate270", "flipHorizontal", "flipVertical", "flipDiagonal", "flipAntiDiagonal"}
+
+func (op SymmetryOp) String() string {
+	if op < 0 || op > FlipAntiDiagonal|SwapLayers {
+		return fmt.Sprintf("SymmetryOp(%d)", int(op))
+	}
+	if op == SwapLayers {
+		return "swapLayers"
+	}
+	if op&SwapLayers != 0 {
+		return symmetryOpNames[op&^SwapLayers] + "+swapLayers"
+	}
+	return symmetryOpNames[op]
+}
+
+// Inverse returns the op that undoes this one.
+func (op SymmetryOp) Inverse() SymmetryOp {
+	switch op &^ SwapLayers {
+	case Rotate90:
+		return Rotate270 | op&SwapLayers
+	case Rotate270:
+		return Rotate90 | op&SwapLayers
+	default:
+		return op
+	}
+}
+
+// swapsDimensions returns true if the op turns a width by height level into a height by width one.
+func (op SymmetryOp) swapsDimensions() bool {
+	switch op &^ SwapLayers {
+	case Rotate90, Rotate270, FlipDiagonal, FlipAntiDiagonal:
+		return true
+	default:
+		return false
+	}
+}
+
+// transformPoint maps a tile of a width by height level.
+func (op SymmetryOp) transformPoint(p Point, width, height int) Point {
+	x, y := p.X, p.Y
+	switch op &^ SwapLayers {
+	case Rotate90:
+		return Point{X: height - 1 - y, Y: x}
+	case Rotate180:
+		return Point{X: width - 1 - x, Y: height - 1 - y}
+	case Rotate270:
+		return Point{X: y, Y: width - 1 - x}
+	case FlipHorizontal:
+		return Point{X: width - 1 - x, Y: y}
+	case FlipVertical:
+		return Point{X: x, Y: height - 1 - y}
+	case FlipDiagonal:
+		return Point{X: y, Y: x}
+	case FlipAntiDiagonal:
+		return Point{X: height - 1 - y, Y: width - 1 - x}
+	default:
+		return p
+	}
+}
+
+// transformDirection maps a direction, such as a move's.
+func (op SymmetryOp) transformDirection(direction Point) Point {
+	// A 1x1 level has its only tile at the origin, so this leaves just the rotation or reflection.
+	return op.transformPoint(direction, 1, 1)
+}
+
+func (op SymmetryOp) transformLayer(layer CollisionLayer) CollisionLayer {
+	if op&SwapLayers != 0 {
+		return invertCollisionLayer(layer)
+	}
+	return layer
+}
+
+// Transform returns a copy of the level with the op applied.
+// Moves in the transformed level correspond to moves in the original level transformed by TransformMoveInputs.
+func Transform(level *Level, op SymmetryOp) *Level {
+	width, height := level.Info.Width, level.Info.Height
+	transformed := copyLevel(level)
+	if op.swapsDimensions() {
+		transformed.Info.Width, transformed.Info.Height = height, width
+	}
+	transformed.Grid = make([][]CollisionLayer, transformed.Info.Height)
+	for y := range transformed.Grid {
+		transformed.Grid[y] = make([]CollisionLayer, transformed.Info.Width)
+	}
+	for y, row := range level.Grid {
+		for x, layer := range row {
+			p := op.transformPoint(Point{X: x, Y: y}, width, height)
+			transformed.Grid[p.Y][p.X] = op.transformLayer(layer)
+		}
+	}
+	for _, entity := range transformed.Entities {
+		switch e := entity.(type) {
+		case *Food:
+			e.Position, e.Layer = op.transformPoint(e.Position, width, height), op.transformLayer(e.Layer)
+		case *Inverter:
+			e.Position, e.Layer = op.transformPoint(e.Position, width, height), op.transformLayer(e.Layer)
+		case *Crate:
+			e.Position, e.Layer = op.transformPoint(e.Position, width, height), op.transformLayer(e.Layer)
+		case *CellularAutomata:
+			e.Position, e.Layer = op.transformPoint(e.Position, width, height), op.transformLayer(e.Layer)
+		case *Snake:
+			for i, segment := range e.Segments {
+				e.Segments[i] = op.transformPoint(segment, width, height)
+			}
+			e.Layer = op.transformLayer(e.Layer)
+		default:
+			panic("Unknown entity type during level transform")
+		}
+	}
+	return transformed
+}
+
+// TransformMoveInputs returns the moves rotated or reflected by the op,
+// so a solution to a level becomes a solution to the transformed level.
+func TransformMoveInputs(moveInputs []MoveInput, op SymmetryOp) []MoveInput {
+	transformed := make([]MoveInput, len(moveInputs))
+	for i, input := range moveInputs {
+		transformed[i] = MoveInput{Direction: op.transformDirection(input.Direction), SnakeID: input.SnakeID}
+	}
+	return transformed
+}
+
+// Canonicalize returns a representative of the level's equivalence class under SymmetryOps,
+// so equivalent levels give the same canonical level, along with the op that transforms the level into it.
+// To map a solution of the canonical level back to the given level, use TransformMoveInputs with op.Inverse().
+// Snake IDs are kept, so levels that differ only in snake IDs aren't considered equivalent,
+// since solutions refer to snakes by ID.
+func Canonicalize(level *Level) (*Level, SymmetryOp) {
+	var best *Level
+	var bestBytes []byte
+	bestOp := Identity
+	for _, op := range SymmetryOps {
+		transformed := Transform(level, op)
+		b := CanonicalBytes(transformed)
+		if best == nil || bytes.Compare(b, bestBytes) < 0 {
+			best, bestBytes, bestOp = transformed, b, op
+		}
+	}
+	return best, bestOp
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTransformInverse(t *testing.T) {
+	level, err := LoadLevel("levels/sketches/based-on-generated-levels/generated-puzzle.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	if level.Info.Width == level.Info.Height {
+		t.Fatalf("Expected a non-square level to test with")
+	}
+	for _, op := range SymmetryOps {
+		transformed := Transform(level, op)
+		if op.swapsDimensions() != (transformed.Info.Width != level.Info.Width) {
+			t.Errorf("%v: unexpected dimensions %dx%d", op, transformed.Info.Width, transformed.Info.Height)
+		}
+		if op != Identity && Equal(transformed, level) {
+			t.Errorf("%v: expected the level to change", op)
+		}
+		if !Equal(Transform(transformed, op.Inverse()), level) {
+			t.Errorf("%v: expected %v to undo it", op, op.Inverse())
+		}
+	}
+	if !Equal(Transform(Transform(level, Rotate90), Rotate90), Transform(level, Rotate180)) {
+		t.Errorf("Expected rotating by 90 degrees twice to be the same as rotating by 180")
+	}
+	if !Equal(Transform(Transform(level, FlipHorizontal), Rotate90), Transform(level, FlipAntiDiagonal)) {
+		t.Errorf("Expected flipping horizontally and then rotating by 90 degrees to be the same as flipping across the anti-diagonal")
+	}
+}
+
+func TestTransformedPlaythroughsStillWin(t *testing.T) {
+	for _, levelId := range []string{
+		"levels/easy/001-movement.json",
+		"levels/easy/003-bridge.json",
+		"levels/easy/004-ferry.json",
+	} {
+		level, err := LoadLevel(levelId)
+		if err != nil {
+			t.Fatalf("Failed to load level: %v", err)
+		}
+		_, moveInputs, err := LoadPlaythrough(levelId[:len(levelId)-len(".json")] + "-playthrough.json")
+		if err != nil {
+			t.Fatalf("Failed to load playthrough: %v", err)
+		}
+		for _, op := range SymmetryOps {
+			final, ok := applyMoveInputs(Transform(level, op), TransformMoveInputs(moveInputs, op)...)
+			if !ok || !levelIsWon(final) {
+				t.Errorf("%s: expected the playthrough transformed by %v to win the transformed level", levelId, op)
+			}
+		}
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	level, err := LoadLevel("levels/easy/004-ferry.json")
+	if err != nil {
+		t.Fatalf("Failed to load level: %v", err)
+	}
+	_, moveInputs, err := LoadPlaythrough("levels/easy/004-ferry-playthrough.json")
+	if err != nil {
+		t.Fatalf("Failed to load playthrough: %v", err)
+	}
+	canonical, canonicalOp := Canonicalize(level)
+	if !Equal(Transform(level, canonicalOp), canonical) {
+		t.Errorf("Expected the returned op %v to give the canonical level", canonicalOp)
+	}
+	canonicalSolution := TransformMoveInputs(moveInputs, canonicalOp)
+
+	for _, op := range SymmetryOps {
+		transformed := Transform(level, op)
+		other, otherOp := Canonicalize(transformed)
+		if !Equal(other, canonical) {
+			t.Errorf("Expected the level transformed by %v to have the same canonical form", op)
+		}
+		// A solution found for the canonical level can be shared with equivalent levels.
+		final, ok := applyMoveInputs(transformed, TransformMoveInputs(canonicalSolution, otherOp.Inverse())...)
+		if !ok || !levelIsWon(final) {
+			t.Errorf("Expected the canonical solution, mapped back by %v, to win the level transformed by %v", otherOp.Inverse(), op)
+		}
+	}
+}
+
+func TestSymmetryOpString(t *testing.T) {
+	for op, expected := range map[SymmetryOp]string{
+		Identity:                  "identity",
+		Rotate90:                  "rotate90",
+		SwapLayers:                "swapLayers",
+		FlipDiagonal | SwapLayers: "flipDiagonal+swapLayers",
+		SymmetryOp(42):            "SymmetryOp(42)",
+	} {
+		if op.String() != expected {
+			t.Errorf("Expected %q, but got %q", expected, op.String())
+		}
+	}
+}
@@ -22,6 +22,15 @@ type Entity interface {
 	Draw(g *Game)
 }
 
+// AKA: collectibles, pickups, pick-ups, tokens, items, perhaps power-ups
+type Collectable interface {
+	Entity
+	GetPosition() Point
+	// Collect applies the effect of the collectable to the snake that ate it.
+	// The collectable has already been removed from the level at this point.
+	Collect(snake *Snake, level *Level)
+}
+
 type Food struct {
 	Position Point
 	Layer    CollisionLayer
@@ -29,6 +38,10 @@ type Food struct {
 
 func (food *Food) IsSolid() bool            { return false }
 func (food *Food) GetLayer() CollisionLayer { return food.Layer }
+func (food *Food) GetPosition() Point       { return food.Position }
+func (food *Food) Collect(snake *Snake, level *Level) {
+	snake.GrowOnNextMove = true
+}
 func (food *Food) At(x, y int, options HitTestOptions) *Hit {
 	if food.Position.X == x && food.Position.Y == y {
 		return &Hit{
@@ -40,11 +53,70 @@ func (food *Food) At(x, y int, options HitTestOptions) *Hit {
 	return nil
 }
 
+type Inverter struct {
+	Position Point
+	Layer    CollisionLayer
+}
+
+func (inverter *Inverter) IsSolid() bool            { return false }
+func (inverter *Inverter) GetLayer() CollisionLayer { return inverter.Layer }
+func (inverter *Inverter) GetPosition() Point       { return inverter.Position }
+func (inverter *Inverter) Collect(snake *Snake, level *Level) {
+	InvertSnake(snake, level)
+}
+func (inverter *Inverter) At(x, y int, options HitTestOptions) *Hit {
+	if inverter.Position.X == x && inverter.Position.Y == y {
+		return &Hit{
+			Entity:       inverter,
+			SegmentIndex: -1, // Not applicable for Inverter
+			Layer:        inverter.Layer,
+		}
+	}
+	return nil
+}
+
+type Crate struct {
+	Position Point
+	Layer    CollisionLayer
+}
+
+func (crate *Crate) IsSolid() bool            { return true }
+func (crate *Crate) GetLayer() CollisionLayer { return crate.Layer }
+func (crate *Crate) At(x, y int, options HitTestOptions) *Hit {
+	if crate.Position.X == x && crate.Position.Y == y {
+		return &Hit{
+			Entity:       crate,
+			SegmentIndex: -1, // Not applicable for Crate
+			Layer:        crate.Layer,
+		}
+	}
+	return nil
+}
+
+type CellularAutomata struct {
+	Position Point
+	Layer    CollisionLayer
+}
+
+func (cell *CellularAutomata) IsSolid() bool            { return true }
+func (cell *CellularAutomata) GetLayer() CollisionLayer { return cell.Layer }
+func (cell *CellularAutomata) At(x, y int, options HitTestOptions) *Hit {
+	if cell.Position.X == x && cell.Position.Y == y {
+		return &Hit{
+			Entity:       cell,
+			SegmentIndex: -1, // Not applicable for CellularAutomata
+			Layer:        cell.Layer,
+		}
+	}
+	return nil
+}
+
 type Snake struct {
 	ID             string
 	Segments       []Point // ordered from head to tail
 	GrowOnNextMove bool
 	Layer          CollisionLayer
+	FusedSnakeIDs  []string // snakes dragged along by their tails when this snake moves
 }
 
 func (snake *Snake) IsSolid() bool            { return true }
@@ -68,6 +140,16 @@ func (snake *Snake) At(x, y int, options HitTestOptions) *Hit {
 type LevelInfo struct {
 	Width  int `json:"width"`
 	Height int `json:"height"`
+	// Par is the optimal number of moves to win the level, or 0 if not known.
+	// It's metadata, so it's not considered by Equal or CanonicalBytes.
+	Par int `json:"par,omitempty"`
+	// Generator is set for generated levels, to be able to reproduce them.
+	Generator *GeneratorInfo `json:"generator,omitempty"`
+}
+
+type GeneratorInfo struct {
+	Seed   int64            `json:"seed"`
+	Config *GeneratorConfig `json:"config,omitempty"` // omitted for the default config
 }
 
 // Note: Custom marshaling is defined elsewhere for the Level struct.
@@ -89,13 +171,13 @@ type HitTestOptions struct {
 }
 
 type Move struct {
-	Snake         *Snake
-	To            Point
-	Delta         Point
-	Valid         bool
-	Encumbered    bool
-	EntitiesThere []Entity
-	// EntitiesToPush []Entity
+	Snake          *Snake
+	To             Point
+	Delta          Point
+	Valid          bool
+	Encumbered     bool
+	EntitiesThere  []Entity
+	EntitiesToPush []Entity
 }
 
 type MoveInput struct {
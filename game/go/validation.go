@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidLevel = errors.New("invalid level")
+
+// Diagnostic describes a single problem with a level.
+type Diagnostic struct {
+	Position *Point // nil if the problem isn't tied to a tile
+	// Index into level.Entities for Validate, or into the file's entities for DeserializeLevel.
+	// -1 if not applicable.
+	EntityIndex int
+	Message     string
+}
+
+func (d Diagnostic) String() string {
+	var sb strings.Builder
+	if d.Position != nil {
+		fmt.Fprintf(&sb, "(%d, %d): ", d.Position.X, d.Position.Y)
+	}
+	if d.EntityIndex >= 0 {
+		fmt.Fprintf(&sb, "entity %d: ", d.EntityIndex)
+	}
+	sb.WriteString(d.Message)
+	return sb.String()
+}
+
+// ValidationError wraps ErrInvalidLevel, so it can be checked with errors.Is,
+// while errors.As gives access to the individual diagnostics.
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrInvalidLevel, joinDiagnostics(e.Diagnostics))
+}
+
+func joinDiagnostics(diagnostics []Diagnostic) string {
+	lines := make([]string, len(diagnostics))
+	for i, d := range diagnostics {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "; ")
+}
+
+func (e *ValidationError) Unwrap() error { return ErrInvalidLevel }
+
+func diagnosticAt(position Point, entityIndex int, format string, args ...any) Diagnostic {
+	return Diagnostic{Position: &position, EntityIndex: entityIndex, Message: fmt.Sprintf(format, args...)}
+}
+
+func validLayer(layer CollisionLayer) bool {
+	return layer >= Neither && layer <= Both
+}
+
+// Validate checks a loaded level for problems that would make it behave strangely or crash the game.
+// It returns nil if there are no problems.
+// Problems that can't be represented in a Level (e.g. blocks outside the grid)
+// are caught by DeserializeLevel instead.
+func Validate(level *Level) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	if level.Info.Width <= 0 || level.Info.Height <= 0 {
+		diagnostics = append(diagnostics, Diagnostic{EntityIndex: -1, Message: fmt.Sprintf("invalid level size %dx%d", level.Info.Width, level.Info.Height)})
+	}
+	for y, row := range level.Grid {
+		for x, layer := range row {
+			if !validLayer(layer) {
+				diagnostics = append(diagnostics, diagnosticAt(Point{X: x, Y: y}, -1, "grid cell has invalid layer %d", layer))
+			}
+		}
+	}
+
+	snakeIndices := map[string]int{}
+	for i, entity := range level.Entities {
+		switch e := entity.(type) {
+		case *Snake:
+			if len(e.Segments) == 0 {
+				diagnostics = append(diagnostics, Diagnostic{EntityIndex: i, Message: fmt.Sprintf("snake '%s' has no segments", e.ID)})
+				continue
+			}
+			if e.ID == "" {
+				diagnostics = append(diagnostics, diagnosticAt(e.Segments[0], i, "snake has no ID"))
+			} else if other, ok := snakeIndices[e.ID]; ok {
+				diagnostics = append(diagnostics, diagnosticAt(e.Segments[0], i, "snake ID '%s' is already used by entity %d", e.ID, other))
+			} else {
+				snakeIndices[e.ID] = i
+			}
+			// Snakes can't be on both layers or neither, since they need to be inverted by Inverters
+			if e.Layer != White && e.Layer != Black {
+				diagnostics = append(diagnostics, diagnosticAt(e.Segments[0], i, "snake '%s' has invalid layer %d", e.ID, e.Layer))
+			}
+			for j, segment := range e.Segments {
+				if !withinLevel(segment, level) {
+					diagnostics = append(diagnostics, diagnosticAt(segment, i, "snake '%s' segment %d is out of bounds", e.ID, j))
+				}
+				if j > 0 {
+					prev := e.Segments[j-1]
+					if abs(segment.X-prev.X)+abs(segment.Y-prev.Y) != 1 {
+						diagnostics = append(diagnostics, diagnosticAt(segment, i, "snake '%s' segment %d is not adjacent to segment %d", e.ID, j, j-1))
+					}
+				}
+			}
+		default:
+			position, layer := entityPositionAndLayer(entity)
+			if !withinLevel(position, level) {
+				diagnostics = append(diagnostics, diagnosticAt(position, i, "%s is out of bounds", entityTypeName(entity)))
+			}
+			if !validLayer(layer) {
+				diagnostics = append(diagnostics, diagnosticAt(position, i, "%s has invalid layer %d", entityTypeName(entity), layer))
+			} else if _, ok := entity.(Collectable); ok && layer == Neither {
+				diagnostics = append(diagnostics, diagnosticAt(position, i, "%s is on neither layer, so it can never be collected", entityTypeName(entity)))
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+func entityPositionAndLayer(entity Entity) (Point, CollisionLayer) {
+	switch e := entity.(type) {
+	case *Food:
+		return e.Position, e.Layer
+	case *Inverter:
+		return e.Position, e.Layer
+	case *Crate:
+		return e.Position, e.Layer
+	case *CellularAutomata:
+		return e.Position, e.Layer
+	}
+	panic("Unknown entity type during validation")
+}
+
+// Names as used in the entityTypes of the level format
+func entityTypeName(entity Entity) string {
+	switch entity.(type) {
+	case *Food:
+		return "Food"
+	case *Inverter:
+		return "Inverter"
+	case *Crate:
+		return "Crate"
+	case *CellularAutomata:
+		return "CellularAutomata"
+	case *Snake:
+		return "Snake"
+	}
+	panic("Unknown entity type")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadLevelReportsOutOfBoundsBlock(t *testing.T) {
+	_, err := LoadLevel("levels/tests/inverter-should-cascade-to-overlapped-snakes.json")
+	if !errors.Is(err, ErrInvalidLevel) {
+		t.Fatalf("Expected ErrInvalidLevel, but got %v", err)
+	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a *ValidationError, but got %T", err)
+	}
+	if len(validationErr.Diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, but got %v", validationErr.Diagnostics)
+	}
+	diagnostic := validationErr.Diagnostics[0]
+	if diagnostic.Position == nil || *diagnostic.Position != (Point{X: 6, Y: 6}) || diagnostic.EntityIndex != 0 {
+		t.Errorf("Expected diagnostic for entity 0 at (6, 6), but got %v", diagnostic)
+	}
+}
+
+func TestDeserializeLevelReportsMalformedEntities(t *testing.T) {
+	_, err := DeserializeLevel([]byte(`{
+		"format": "snakeshift",
+		"formatVersion": 6,
+		"levelInfo": {"width": 4, "height": 4},
+		"entities": [{"x": 0, "y": 0}, {}],
+		"entityTypes": ["Teleporter"]
+	}`))
+	if !errors.Is(err, ErrInvalidLevel) {
+		t.Fatalf("Expected ErrInvalidLevel, but got %v", err)
+	}
+	for _, expected := range []string{"2 entities but 1 entity types", "unknown entity type \"Teleporter\""} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to mention %q, but got %v", expected, err)
+		}
+	}
+}
+
+func TestDeserializeLevelReportsHugeBlock(t *testing.T) {
+	// The block is reported, rather than filled in tile by tile.
+	_, err := DeserializeLevel([]byte(`{
+		"format": "snakeshift",
+		"formatVersion": 6,
+		"levelInfo": {"width": 4, "height": 4},
+		"entities": [{"x": 1, "y": 2, "width": 1000000000, "height": 1000000000, "layer": 1}],
+		"entityTypes": ["Block"]
+	}`))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a *ValidationError, but got %v", err)
+	}
+	if len(validationErr.Diagnostics) != 1 || !strings.Contains(validationErr.Diagnostics[0].Message, "out of bounds") {
+		t.Errorf("Expected one out of bounds diagnostic, but got %v", validationErr.Diagnostics)
+	}
+}
+
+func TestDeserializeLevelReportsInvalidSize(t *testing.T) {
+	for _, size := range []string{`"width": -1, "height": 4`, `"width": 4, "height": -3`, `"width": 0, "height": 0`} {
+		_, err := DeserializeLevel([]byte(`{
+			"format": "snakeshift",
+			"formatVersion": 6,
+			"levelInfo": {` + size + `},
+			"entities": [],
+			"entityTypes": []
+		}`))
+		if !errors.Is(err, ErrInvalidLevel) || !strings.Contains(err.Error(), "invalid level size") {
+			t.Errorf("%s: expected an invalid level size error, but got %v", size, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		entity   Entity
+		expected string
+		position *Point
+	}{
+		{"empty snake", &Snake{ID: "b", Layer: White}, "snake 'b' has no segments", nil},
+		{"non-contiguous snake", &Snake{ID: "b", Layer: White, Segments: []Point{{X: 0, Y: 0}, {X: 2, Y: 0}}}, "segment 1 is not adjacent to segment 0", &Point{X: 2, Y: 0}},
+		{"duplicate snake ID", &Snake{ID: "a", Layer: Black, Segments: []Point{{X: 3, Y: 3}}}, "snake ID 'a' is already used by entity 0", &Point{X: 3, Y: 3}},
+		{"snake out of bounds", &Snake{ID: "b", Layer: White, Segments: []Point{{X: 3, Y: 3}, {X: 3, Y: 4}}}, "segment 1 is out of bounds", &Point{X: 3, Y: 4}},
+		{"food on neither layer", &Food{Position: Point{X: 1, Y: 2}, Layer: Neither}, "can never be collected", &Point{X: 1, Y: 2}},
+		{"food on invalid layer", &Food{Position: Point{X: 1, Y: 2}, Layer: Invalid}, "invalid layer", &Point{X: 1, Y: 2}},
+		{"crate out of bounds", &Crate{Position: Point{X: -1, Y: 0}, Layer: White}, "Crate is out of bounds", &Point{X: -1, Y: 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			level := &Level{
+				Info:     LevelInfo{Width: 4, Height: 4},
+				Grid:     [][]CollisionLayer{{Black, Black, Black, Black}, {Black, Black, Black, Black}, {Black, Black, Black, Black}, {Black, Black, Black, Black}},
+				Entities: []Entity{&Snake{ID: "a", Layer: White, Segments: []Point{{X: 0, Y: 1}}}, test.entity},
+			}
+			diagnostics := Validate(level)
+			if len(diagnostics) != 1 {
+				t.Fatalf("Expected 1 diagnostic, but got %v", diagnostics)
+			}
+			if !strings.Contains(diagnostics[0].Message, test.expected) {
+				t.Errorf("Expected diagnostic to contain %q, but got %q", test.expected, diagnostics[0].Message)
+			}
+			if diagnostics[0].EntityIndex != 1 {
+				t.Errorf("Expected diagnostic for entity 1, but got %d", diagnostics[0].EntityIndex)
+			}
+			if (test.position == nil) != (diagnostics[0].Position == nil) || (test.position != nil && *test.position != *diagnostics[0].Position) {
+				t.Errorf("Expected diagnostic at %v, but got %v", test.position, diagnostics[0].Position)
+			}
+		})
+	}
+}
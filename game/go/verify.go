@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Playthroughs with these in their names are kept around for reference,
+// but aren't expected to win under the current rules.
+var knownInvalidPlaythroughMarkers = []string{
+	"no-longer-allowed",
+	"buggy",
+	"outside-the-level-bounds",
+}
+
+type PlaythroughReport struct {
+	PlaythroughId string
+	Moves         int
+	// ExpectedToWin is false for playthroughs named as using old or buggy rules.
+	// It's decided from the name alone, not by replaying, so that a rule change can't excuse itself.
+	ExpectedToWin bool
+	// RecordedWins is true if the last recorded state is a winning state.
+	// Recordings can stop short of winning, when the final move can't be inferred.
+	RecordedWins bool
+	// Wins is true if replaying the moves from the initial state, with no invalid moves, wins the level.
+	Wins bool
+	// FirstDivergence is the index of the first move that, applied to the recorded state before it,
+	// doesn't produce the recorded state after it, or -1 if every move reproduces the recording.
+	// The order of entities that don't overlap isn't compared, since the TS version orders them differently.
+	FirstDivergence int
+	// InvalidMoves are the indices of moves that are invalid when applied to the recorded state before them.
+	InvalidMoves []int
+	// Err is set if the playthrough couldn't be loaded at all.
+	Err error
+}
+
+// Regression reports whether a playthrough that should win no longer reproduces the recorded states,
+// or no longer wins, if the recording got that far.
+func (report PlaythroughReport) Regression() bool {
+	return report.Err != nil || (report.ExpectedToWin && (report.FirstDivergence >= 0 || (report.RecordedWins && !report.Wins)))
+}
+
+func (report PlaythroughReport) String() string {
+	if report.Err != nil {
+		return fmt.Sprintf("%s: %v", report.PlaythroughId, report.Err)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: ", report.PlaythroughId)
+	if report.Wins {
+		fmt.Fprintf(&sb, "wins in %d moves", report.Moves)
+	} else {
+		fmt.Fprintf(&sb, "does not win (%d moves)", report.Moves)
+	}
+	if report.FirstDivergence >= 0 {
+		fmt.Fprintf(&sb, "; first diverges at move %d", report.FirstDivergence)
+	}
+	if len(report.InvalidMoves) > 0 {
+		fmt.Fprintf(&sb, "; invalid moves: %s", strings.Trim(fmt.Sprint(report.InvalidMoves), "[]"))
+	}
+	if !report.ExpectedToWin {
+		sb.WriteString(" (not expected to win)")
+	} else if !report.RecordedWins {
+		sb.WriteString(" (recording is incomplete)")
+	}
+	return sb.String()
+}
+
+// VerifyPlaythrough replays a playthrough through the engine, comparing it to the recorded states.
+func VerifyPlaythrough(playthroughId string) PlaythroughReport {
+	report := PlaythroughReport{
+		PlaythroughId:   playthroughId,
+		ExpectedToWin:   true,
+		FirstDivergence: -1,
+	}
+	for _, marker := range knownInvalidPlaythroughMarkers {
+		if strings.Contains(playthroughId, marker) {
+			report.ExpectedToWin = false
+		}
+	}
+	states, moveInputs, err := LoadPlaythrough(playthroughId)
+	if err != nil {
+		report.Err = err
+		return report
+	}
+	report.Moves = len(moveInputs)
+	report.RecordedWins = levelIsWon(states[len(states)-1])
+
+	// Check each move from the recorded state before it, so that one problem doesn't hide later ones.
+	for i, input := range moveInputs {
+		level := copyLevel(states[i])
+		move, ok := analyzeMoveInput(input, level)
+		if !ok {
+			report.InvalidMoves = append(report.InvalidMoves, i)
+			if report.FirstDivergence == -1 {
+				report.FirstDivergence = i
+			}
+			continue
+		}
+		TakeMove(move, level)
+		if report.FirstDivergence == -1 && !equalIgnoringSeparateOrder(level, states[i+1]) {
+			report.FirstDivergence = i
+		}
+	}
+
+	// Replay the moves continuously from the initial state.
+	level := copyLevel(states[0])
+	report.Wins = true
+	for _, input := range moveInputs {
+		move, ok := analyzeMoveInput(input, level)
+		if !ok {
+			report.Wins = false
+			break
+		}
+		TakeMove(move, level)
+	}
+	report.Wins = report.Wins && levelIsWon(level)
+	return report
+}
+
+// analyzeMoveInput is like AnalyzeMoveRelative, but handles the snake not existing.
+func analyzeMoveInput(input MoveInput, level *Level) (Move, bool) {
+	for _, snake := range getSnakes(level) {
+		if snake.ID == input.SnakeID {
+			move := AnalyzeMoveRelative(snake, input.Direction.X, input.Direction.Y, level)
+			return move, move.Valid
+		}
+	}
+	return Move{}, false
+}
+
+// campaignPlaythroughs finds the playthroughs saved next to each campaign level,
+// named like the level with "-playthrough" and optionally more after it.
+func campaignPlaythroughs() ([]string, error) {
+	levels, err := getLevels()
+	if err != nil {
+		return nil, err
+	}
+	var playthroughIds []string
+	for _, entry := range levels {
+		pattern := strings.TrimSuffix(entry.LevelId, ".json") + "-playthrough*.json"
+		matches, err := filepath.Glob(path.Join("..", "public", pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			relative, err := filepath.Rel(path.Join("..", "public"), match)
+			if err != nil {
+				return nil, err
+			}
+			playthroughIds = append(playthroughIds, filepath.ToSlash(relative))
+		}
+	}
+	return playthroughIds, nil
+}
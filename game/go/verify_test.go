@@ -0,0 +1,123 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVerifyPlaythroughWins(t *testing.T) {
+	report := VerifyPlaythrough("levels/easy/001-movement-playthrough.json")
+	if report.Err != nil {
+		t.Fatalf("Failed to verify playthrough: %v", report.Err)
+	}
+	if !report.Wins || !report.ExpectedToWin || report.Regression() {
+		t.Errorf("Expected playthrough to win with no regression, but got: %s", report)
+	}
+	if len(report.InvalidMoves) > 0 || report.FirstDivergence != -1 {
+		t.Errorf("Expected playthrough to match the recording, but got: %s", report)
+	}
+}
+
+func TestVerifyPlaythroughNoLongerAllowed(t *testing.T) {
+	report := VerifyPlaythrough("levels/hard/security-by-obscurity-lock-playthrough-intended-but-no-longer-allowed.json")
+	if report.Err != nil {
+		t.Fatalf("Failed to verify playthrough: %v", report.Err)
+	}
+	if report.ExpectedToWin || report.Regression() {
+		t.Errorf("Expected playthrough to be exempt, but got: %s", report)
+	}
+	if report.Wins || len(report.InvalidMoves) == 0 || report.FirstDivergence != report.InvalidMoves[0] {
+		t.Errorf("Expected playthrough to have invalid moves, but got: %s", report)
+	}
+}
+
+func TestVerifyPlaythroughDivergenceIsRegression(t *testing.T) {
+	// Winning isn't enough; a recording that the engine no longer reproduces has regressed.
+	report := PlaythroughReport{ExpectedToWin: true, Wins: true, FirstDivergence: 15}
+	if !report.Regression() {
+		t.Errorf("Expected a diverging playthrough to be reported as a regression")
+	}
+	report.ExpectedToWin = false
+	if report.Regression() {
+		t.Errorf("Expected a playthrough named as using old rules to be exempt")
+	}
+}
+
+func TestVerifyPlaythroughIncompleteRecording(t *testing.T) {
+	// A recording that stops before the final move can't be expected to win, but it should still match.
+	report := PlaythroughReport{ExpectedToWin: true, RecordedWins: false, Wins: false, FirstDivergence: -1}
+	if report.Regression() {
+		t.Errorf("Expected an incomplete recording not to be reported as a regression")
+	}
+	report.FirstDivergence = 3
+	if !report.Regression() {
+		t.Errorf("Expected an incomplete recording that diverges to be reported as a regression")
+	}
+	report = PlaythroughReport{ExpectedToWin: true, RecordedWins: true, Wins: false, FirstDivergence: -1}
+	if !report.Regression() {
+		t.Errorf("Expected a recording that won, but no longer wins, to be reported as a regression")
+	}
+}
+
+func TestEqualIgnoringSeparateOrder(t *testing.T) {
+	level := &Level{
+		Info: LevelInfo{Width: 3, Height: 1},
+		Grid: [][]CollisionLayer{{Black, White, Black}},
+		Entities: []Entity{
+			&Snake{ID: "a", Layer: White, Segments: []Point{{X: 0, Y: 0}}},
+			&Snake{ID: "b", Layer: Black, Segments: []Point{{X: 1, Y: 0}}},
+			&Food{Position: Point{X: 1, Y: 0}, Layer: Black},
+		},
+	}
+	// Snake a doesn't overlap anything, so its order doesn't matter.
+	reordered := copyLevel(level)
+	reordered.Entities = []Entity{reordered.Entities[1], reordered.Entities[2], reordered.Entities[0]}
+	if Equal(level, reordered) || !equalIgnoringSeparateOrder(level, reordered) {
+		t.Errorf("Expected only the order of separate entities to differ")
+	}
+	// Snake b and the food overlap, so which is on top matters.
+	swapped := copyLevel(level)
+	swapped.Entities[1], swapped.Entities[2] = swapped.Entities[2], swapped.Entities[1]
+	if equalIgnoringSeparateOrder(level, swapped) {
+		t.Errorf("Expected the order of overlapping entities to matter")
+	}
+	moved := copyLevel(level)
+	moved.Entities[0].(*Snake).Segments[0] = Point{X: 2, Y: 0}
+	if equalIgnoringSeparateOrder(level, moved) {
+		t.Errorf("Expected a moved snake to make the levels differ")
+	}
+}
+
+func TestVerifyCampaignPlaythroughs(t *testing.T) {
+	playthroughIds, err := campaignPlaythroughs()
+	if err != nil {
+		t.Fatalf("Failed to find playthroughs: %v", err)
+	}
+	for _, playthroughId := range playthroughIds {
+		if report := VerifyPlaythrough(playthroughId); report.Regression() {
+			t.Errorf("Regression: %s", report)
+		}
+	}
+}
+
+func TestVerifyPlaythroughMissing(t *testing.T) {
+	report := VerifyPlaythrough("levels/easy/does-not-exist-playthrough.json")
+	if report.Err == nil || !report.Regression() {
+		t.Errorf("Expected a missing playthrough to be reported as a regression, but got: %s", report)
+	}
+}
+
+func TestCampaignPlaythroughs(t *testing.T) {
+	playthroughIds, err := campaignPlaythroughs()
+	if err != nil {
+		t.Fatalf("Failed to find playthroughs: %v", err)
+	}
+	for _, expected := range []string{
+		"levels/easy/001-movement-playthrough.json",
+		"levels/easy/005-yin-yang-give-and-take-playthrough-shorter.json",
+	} {
+		if !slices.Contains(playthroughIds, expected) {
+			t.Errorf("Expected %s among campaign playthroughs", expected)
+		}
+	}
+}